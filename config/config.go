@@ -1,66 +1,377 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"log"
 	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"0xPet/internal/fsutil"
+	"0xPet/internal/physics"
 )
 
 // Config 结构体：对应 config.json 的内容
 type Config struct {
+	// 【新增】配置格式版本，旧文件没有这个字段 (视为 0)，读取时会自动升级到 CurrentVersion
+	Version int `json:"version"`
+
 	ImagePath     string `json:"image_path"`     // 上次用的图片路径
 	ShowColor     bool   `json:"show_color"`     // 是否开启彩色
 	ShowGlitch    bool   `json:"show_glitch"`    // 是否开启乱码
 	ShowAnimation bool   `json:"show_animation"` // 是否开启浮动
 	ShowMonitor   bool   `json:"show_monitor"`   // 是否开启监控文字
+	DisplayMode   int    `json:"display_mode"`   // 【新增】显示模式 (0=正常 1=高分辨率 2=迷你)
+	Ramp          string `json:"ramp,omitempty"` // 【新增】字符画使用的字符集，从密集到稀疏，留空用默认
+
+	// 【新增】配色主题：内置主题名或用户主题目录下的文件名 (不带 .json)，留空用默认
+	Theme string `json:"theme,omitempty"`
+
+	// 【新增】告警规则，缺省时使用 DefaultRules；写成 [] 表示不要任何规则
+	Rules []RuleConfig `json:"rules"`
+
+	// 【新增】Prometheus 指标端点
+	MetricsEnabled bool   `json:"metrics_enabled"` // 是否开启本地 /metrics 端点
+	MetricsAddr    string `json:"metrics_addr"`    // 绑定地址，比如 "127.0.0.1:9464"
+
+	// 【新增】远程监控：填写后宠物显示远程 agent 那台机器的状态
+	RemoteURL  string `json:"remote_url,omitempty"`  // agent 地址，比如 "http://buildbox:9465"
+	RemoteMode string `json:"remote_mode,omitempty"` // "json" (轮询) 或 "sse" (推送)
+
+	// 【新增】容器/systemd slice 模式：CPU 与内存按本 cgroup 的限额计算
+	Cgroup     bool   `json:"cgroup,omitempty"`      // 是否启用
+	CgroupRoot string `json:"cgroup_root,omitempty"` // cgroup v2 挂载点，默认 "/sys/fs/cgroup"
+
+	// 【新增】电池与温度：低电量犯困、过热出汗
+	LowBattery float64 `json:"low_battery"` // 用电池供电且电量低于该值 (%) 时犯困，0 表示关闭
+	HotTemp    float64 `json:"hot_temp"`    // 最高传感器温度超过该值 (°C) 时出汗，0 表示关闭
+
+	// 【新增】甩出去之后的物理手感
+	// 升级提示：早期版本在读取时会把 "max_speed": 0 换成默认的 4000，现在 0 按字面意思表示不限速；
+	// 想保留原来手感的用户把它删掉 (缺省时用默认值) 或写成 4000 即可
+	Friction    float64 `json:"friction"`    // 每秒的速度衰减系数，0 表示没有摩擦
+	Restitution float64 `json:"restitution"` // 撞到屏幕边缘后保留的速度比例 (0~1)
+	MaxSpeed    float64 `json:"max_speed"`   // 甩出去的速度上限 (像素/秒)，0 表示不限制
+	Gravity     bool    `json:"gravity"`     // 重力模式：松手后落到屏幕底部，弹几下后坐在那里
+	Floor       float64 `json:"floor"`       // 地面离屏幕底边的距离 (像素)，比如任务栏的高度
+
+	// 【新增】用户自定义指标，可以像 cpu/mem 一样用在 HUD 和告警规则里
+	CustomMetrics []CustomMetric `json:"custom_metrics,omitempty"`
+
+	// 【新增】日志监听：日志里出现指定内容时让宠物做出反应
+	LogWatches []LogWatch `json:"log_watches,omitempty"`
+
+	// 【新增】进程监听：匹配的进程开始/结束时通知宠物
+	ProcessWatches []ProcessWatch `json:"process_watches,omitempty"`
+
+	// 【新增】本机控制端点，`0xpet watch` 通过它把命令进度转发给正在运行的宠物；只能是本机地址
+	ControlAddr string `json:"control_addr"`
+
+	// 【新增】命名 profile：可以在右键菜单或用 --profile 整体切换，Profile 记住上次用的是哪个
+	Profile  string             `json:"profile,omitempty"`
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+}
+
+// ProcessWatch 需要关注的进程，比如 {"pattern": "^cargo", "busy_after": "10s"}
+type ProcessWatch struct {
+	Name      string `json:"name,omitempty"`       // 显示名，留空时用 pattern
+	Pattern   string `json:"pattern"`              // 正则，匹配进程名或完整命令行，比如 "go build"
+	BusyAfter string `json:"busy_after,omitempty"` // 运行超过多久算长任务 (宠物进入忙碌状态并在结束时提醒)，默认 "5s"
+}
+
+// DefaultControlAddr 控制端点的默认绑定地址 (只监听本机)
+const DefaultControlAddr = "127.0.0.1:9466"
+
+// 电池与温度的默认阈值
+const (
+	DefaultLowBattery = 20.0 // %
+	DefaultHotTemp    = 85.0 // °C
+)
+
+// LogWatch 一个被跟踪的日志文件及其匹配规则
+type LogWatch struct {
+	Path  string    `json:"path"`
+	Rules []LogRule `json:"rules"`
+}
+
+// LogRule 日志匹配规则，比如 {"pattern": "panic: (.*)", "state": "stressed", "bubble": "{1}"}
+type LogRule struct {
+	Pattern  string `json:"pattern"`            // 正则表达式
+	State    string `json:"state,omitempty"`    // 命中时切换的状态
+	Color    string `json:"color,omitempty"`    // 命中时的着色
+	Bubble   string `json:"bubble,omitempty"`   // 气泡，可用 {match} {line} {1} {2} ...
+	Glitch   bool   `json:"glitch,omitempty"`   // 命中时来一次乱码爆发
+	Duration string `json:"duration,omitempty"` // 状态与着色保持多久，默认 "10s"
+}
+
+// CustomMetric 一个自定义指标的来源定义
+type CustomMetric struct {
+	Name     string  `json:"name"`               // 指标名，规则里直接引用，比如 "queue > 100"
+	Type     string  `json:"type"`               // "command"、"file" 或 "socket"
+	Command  string  `json:"command,omitempty"`  // type=command：要执行的 shell 命令
+	Path     string  `json:"path,omitempty"`     // type=file/socket：文件或 Unix socket 路径
+	Field    string  `json:"field,omitempty"`    // 从 JSON 中取值的字段，比如 "stats.depth"；留空则输出必须是数字
+	Interval string  `json:"interval,omitempty"` // 采样间隔，默认 "10s"
+	Timeout  string  `json:"timeout,omitempty"`  // 单次采样超时，默认 "5s"
+	HUD      bool    `json:"hud,omitempty"`      // 是否在 HUD 上显示
+	Max      float64 `json:"max,omitempty"`      // HUD 进度条的满格值，默认 100
+}
+
+// DefaultMetricsAddr 指标端点的默认绑定地址 (只监听本机)
+const DefaultMetricsAddr = "127.0.0.1:9464"
+
+// RuleConfig 一条告警规则的配置，比如 {"expr": "cpu > 85 for 10s", "state": "stressed"}
+type RuleConfig struct {
+	Name     string `json:"name,omitempty"`     // 规则名，留空时用表达式本身
+	Expr     string `json:"expr"`               // 表达式："<指标> <比较符> <阈值> [for <时长>]"
+	State    string `json:"state,omitempty"`    // 触发时切换的状态，比如 "stressed"
+	Color    string `json:"color,omitempty"`    // 触发时的着色，比如 "#ff3232"
+	Bubble   string `json:"bubble,omitempty"`   // 触发时的气泡，可用 {value} {metric} {name}
+	Glitch   bool   `json:"glitch,omitempty"`   // 触发时来一次乱码爆发
+	Cooldown string `json:"cooldown,omitempty"` // 两次触发的最短间隔，比如 "1m"
+}
+
+// DefaultRules 默认规则：等价于旧版写死的 "CPU > 80 就变红"
+func DefaultRules() []RuleConfig {
+	return []RuleConfig{
+		{Name: "cpu-high", Expr: "cpu > 80", State: "stressed", Color: "#ff3232"},
+	}
 }
 
 // NewDefault 生成一份默认配置
 // 当找不到配置文件，或者读取失败时，用这个“保底”
 func NewDefault() *Config {
 	return &Config{
+		Version:       CurrentVersion,
 		ImagePath:     "assets/idle.png", // 默认图
 		ShowColor:     true,
 		ShowGlitch:    true,
 		ShowAnimation: true,
 		ShowMonitor:   false,
+		Rules:         DefaultRules(),
+		MetricsAddr:   DefaultMetricsAddr,
+		ControlAddr:   DefaultControlAddr,
+		LowBattery:    DefaultLowBattery,
+		HotTemp:       DefaultHotTemp,
+		Friction:      physics.DefaultFriction,
+		Restitution:   physics.DefaultRestitution,
+		MaxSpeed:      physics.DefaultMaxSpeed,
 	}
 }
 
-// Load 从硬盘读取配置
+// Load 从硬盘读取配置，旧版本的文件会先升级到当前格式；
+// 文件不存在时返回默认配置，内容有误时返回错误，由调用方决定是否退回默认值。
+// 文件损坏 (或者上次保存中途崩溃只剩下备份) 而 .bak 完好时，会用备份恢复
 func Load(filename string) (*Config, error) {
-	// 1. 尝试打开文件
-	file, err := os.Open(filename)
+	// 1. 读取文件
+	data, err := os.ReadFile(filename)
 	if err != nil {
-		// 如果文件不存在，直接返回默认配置，不算报错
 		if os.IsNotExist(err) {
+			if cfg, ok := restoreBackup(filename, nil); ok {
+				return cfg, nil
+			}
+			// 如果文件不存在，直接返回默认配置，不算报错
 			return NewDefault(), nil
 		}
 		return nil, err
 	}
-	defer file.Close()
 
-	// 2. 解析 JSON
-	cfg := &Config{}
-	decoder := json.NewDecoder(file)
-	if err := decoder.Decode(cfg); err != nil {
-		// 如果 JSON 格式坏了，也返回默认配置
-		return NewDefault(), nil
+	// 2. 解析、升级并校验；警告只记日志，错误带上文件名与行列号返回
+	cfg, probs := Check(data)
+	var errs []Problem
+	for _, p := range probs {
+		if p.Warning {
+			log.Println(p.In(filename))
+		} else {
+			errs = append(errs, p)
+		}
+	}
+	if cfg == nil {
+		verr := &ValidationError{File: filename, Problems: errs}
+		if cfg, ok := restoreBackup(filename, verr); ok {
+			return cfg, nil
+		}
+		return nil, verr
+	}
+	return cfg, nil
+}
+
+// restoreBackup 用 filename.bak 恢复配置；损坏的原文件改名为 .corrupt 留给用户查看，不会丢掉手改的内容
+func restoreBackup(filename string, cause error) (*Config, bool) {
+	backup := filename + fsutil.BackupSuffix
+	data, err := os.ReadFile(backup)
+	if err != nil {
+		return nil, false
+	}
+	cfg, _ := Check(data)
+	if cfg == nil {
+		return nil, false
+	}
+
+	if cause != nil {
+		log.Println("配置文件损坏:", cause)
+		if err := os.Rename(filename, filename+".corrupt"); err != nil {
+			log.Println("无法保留损坏的配置文件:", err)
+			return cfg, true // 原文件还在，就不去覆盖它了，只是这次用备份的内容运行
+		}
 	}
+	if err := fsutil.WriteAtomic(filename, data, 0644); err != nil {
+		log.Println("从备份恢复配置失败:", err)
+	} else {
+		log.Printf("已从 %s 恢复配置", backup)
+	}
+	return cfg, true
+}
 
+// Parse 解析并校验一份配置文件的内容，有错误时返回 *ValidationError
+func Parse(data []byte) (*Config, error) {
+	cfg, probs := Check(data)
+	if cfg != nil {
+		return cfg, nil
+	}
+	var errs []Problem
+	for _, p := range probs {
+		if !p.Warning {
+			errs = append(errs, p)
+		}
+	}
+	return nil, &ValidationError{Problems: errs}
+}
+
+// decode 先按版本做迁移，再覆盖到默认配置上，这样文件里没写的字段保持默认值
+func decode(data []byte) (*Config, error) {
+	raw := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	if _, err := Migrate(raw); err != nil {
+		return nil, err
+	}
+	upgraded, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := NewDefault()
+	if err := json.Unmarshal(upgraded, cfg); err != nil {
+		return nil, err
+	}
+	cfg.normalize()
 	return cfg, nil
 }
 
+// normalize 把表示 "用默认值" 的空值换成默认值 (真正无效的取值由 Check 报错)
+func (cfg *Config) normalize() {
+	if cfg.Rules == nil {
+		cfg.Rules = DefaultRules()
+	}
+	if cfg.MetricsAddr == "" {
+		cfg.MetricsAddr = DefaultMetricsAddr
+	}
+	if cfg.ControlAddr == "" {
+		cfg.ControlAddr = DefaultControlAddr
+	}
+}
+
 // Save 把当前配置写入硬盘
+// 采用 "读-改-写"：文件里本程序不认识的字段 (比如更新版本写入的设置、用户自己的备注) 会原样保留
 func Save(cfg *Config, filename string) error {
-	// 1. 创建/覆盖文件
-	file, err := os.Create(filename)
+	out := *cfg
+	if out.Version < CurrentVersion {
+		out.Version = CurrentVersion
+	}
+
+	// 1. 已知字段按结构体顺序输出
+	data, err := Marshal(&out)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
 
-	// 2. 写入 JSON (SetIndent 让生成的 JSON 带缩进，方便人类阅读)
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	return encoder.Encode(cfg)
+	// 2. 把旧文件里的未知字段接在后面
+	extra, err := unknownFields(filename)
+	if err != nil {
+		return err
+	}
+	if len(extra) > 0 {
+		data, err = appendFields(data, extra)
+		if err != nil {
+			return err
+		}
+	}
+
+	// 3. 原子地写回文件，上一版保留为 .bak
+	return fsutil.WriteAtomic(filename, append(data, '\n'), 0644)
+}
+
+// Marshal 按保存时的格式输出配置：带缩进方便人类阅读，规则表达式里的 > < & 不转义成 \u003e 之类
+func Marshal(cfg *Config) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(cfg); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// unknownFields 读取现有配置文件中不属于 Config 的顶层字段；文件不存在或不是合法 JSON 时返回空
+func unknownFields(filename string) (map[string]json.RawMessage, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	raw := map[string]json.RawMessage{}
+	if json.Unmarshal(data, &raw) != nil {
+		return nil, nil
+	}
+	known := knownFields()
+	for key := range raw {
+		if known[key] {
+			delete(raw, key)
+		}
+	}
+	return raw, nil
+}
+
+// knownFields 从 Config 的 json 标签中收集顶层字段名
+func knownFields() map[string]bool {
+	known := map[string]bool{}
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			known[name] = true
+		}
+	}
+	return known
+}
+
+// appendFields 把 extra 里的字段按名字顺序追加到一个缩进好的 JSON 对象末尾
+func appendFields(obj []byte, extra map[string]json.RawMessage) ([]byte, error) {
+	keys := make([]string, 0, len(extra))
+	for k := range extra {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.Write(bytes.TrimSuffix(obj, []byte("\n}")))
+	for _, k := range keys {
+		name, _ := json.Marshal(k)
+		buf.WriteString(",\n  ")
+		buf.Write(name)
+		buf.WriteString(": ")
+		if err := json.Indent(&buf, extra[k], "  ", "  "); err != nil {
+			return nil, fmt.Errorf("字段 %s: %w", k, err)
+		}
+	}
+	buf.WriteString("\n}")
+	return buf.Bytes(), nil
 }
@@ -0,0 +1,207 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"0xPet/internal/physics"
+)
+
+// sample 一份把大部分字段都改过的配置
+func sample() *Config {
+	cfg := NewDefault()
+	cfg.ImagePath = "/home/me/cat.png"
+	cfg.ShowColor = false
+	cfg.ShowMonitor = true
+	cfg.DisplayMode = 2
+	cfg.Ramp = "@#*. "
+	cfg.Theme = "amber"
+	cfg.Rules = []RuleConfig{
+		{Name: "cpu-high", Expr: "cpu > 85 for 10s", State: "stressed", Color: "#ff3232", Bubble: "cpu {value}% & rising", Cooldown: "1m"},
+		{Expr: "disk:/ >= 95", Glitch: true},
+	}
+	cfg.MetricsEnabled = true
+	cfg.LowBattery = 0
+	cfg.HotTemp = 90
+	cfg.Friction = 1.5
+	cfg.Gravity = true
+	cfg.Floor = 48
+	cfg.CustomMetrics = []CustomMetric{{Name: "queue", Type: "command", Command: "echo '{\"depth\": 3}' | cat", Field: "depth", HUD: true, Max: 50}}
+	cfg.LogWatches = []LogWatch{{Path: "/var/log/app.log", Rules: []LogRule{{Pattern: `panic: (.*)`, Bubble: "<{1}>"}}}}
+	cfg.ProcessWatches = []ProcessWatch{{Pattern: "^cargo", BusyAfter: "10s"}}
+	mode := 1
+	cfg.Profile = "work"
+	cfg.Profiles = map[string]Profile{"work": {DisplayMode: &mode, Rules: &[]RuleConfig{{Expr: "mem > 90"}}}}
+	return cfg
+}
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	want := sample()
+	if err := Save(want, path); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip mismatch\n got: %+v\nwant: %+v", got, want)
+	}
+
+	// 再存一次，文件内容不变
+	first, _ := os.ReadFile(path)
+	if err := Save(got, path); err != nil {
+		t.Fatal(err)
+	}
+	second, _ := os.ReadFile(path)
+	if string(first) != string(second) {
+		t.Errorf("second save changed the file:\n%s\n---\n%s", first, second)
+	}
+}
+
+func TestSaveDoesNotEscapeHTML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := Save(sample(), path); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	for _, want := range []string{`"cpu > 85 for 10s"`, `"disk:/ >= 95"`, `& rising`, `"<{1}>"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("saved file does not contain %s", want)
+		}
+	}
+	if strings.Contains(string(data), `\u00`) {
+		t.Errorf("saved file has escaped characters:\n%s", data)
+	}
+}
+
+func TestSaveKeepsUnknownFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"version": 1, "show_color": true, "future_setting": {"a": [1, 2]}, "_comment": "mine"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.ShowColor = false
+	if err := Save(cfg, path); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(path)
+	for _, want := range []string{`"future_setting"`, `"_comment": "mine"`, `"show_color": false`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("saved file does not contain %s:\n%s", want, data)
+		}
+	}
+	if _, err := Load(path); err != nil {
+		t.Errorf("reloading saved file: %v", err)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	cfg, err := Load(filepath.Join(t.TempDir(), "config.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cfg, NewDefault()) {
+		t.Errorf("missing file gave %+v, want defaults", cfg)
+	}
+}
+
+func TestLoadKeepsExplicitZero(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"version": 1, "low_battery": 0, "hot_temp": 0, "max_speed": 0}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.LowBattery != 0 || cfg.HotTemp != 0 || cfg.MaxSpeed != 0 {
+		t.Errorf("low_battery, hot_temp, max_speed = %v, %v, %v; want explicit 0 kept", cfg.LowBattery, cfg.HotTemp, cfg.MaxSpeed)
+	}
+}
+
+// TestMaxSpeedZero "max_speed": 0 表示不限速：保存、读取、校验都不会把它换成默认值；缺省时才用默认值
+func TestMaxSpeedZero(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	cfg := NewDefault()
+	cfg.MaxSpeed = 0
+	if err := Save(cfg, path); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), `"max_speed": 0`) {
+		t.Errorf("saved file does not contain max_speed 0:\n%s", data)
+	}
+
+	got, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.MaxSpeed != 0 {
+		t.Errorf("max_speed after round trip = %v, want 0 (unlimited)", got.MaxSpeed)
+	}
+	if p, ok := problemAt(Validate(got), "max_speed"); ok {
+		t.Errorf("Validate rejected max_speed 0: %v", p)
+	}
+
+	got.MaxSpeed = -1
+	if _, ok := problemAt(Validate(got), "max_speed"); !ok {
+		t.Error("Validate accepted a negative max_speed")
+	}
+
+	// 没写 max_speed 的旧配置用默认上限
+	if err := os.WriteFile(path, []byte(`{"version": 1}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if got.MaxSpeed != physics.DefaultMaxSpeed {
+		t.Errorf("max_speed without the field = %v, want default %v", got.MaxSpeed, physics.DefaultMaxSpeed)
+	}
+}
+
+// TestProfileRules profile 里的规则整体替换顶层规则；写成 [] 的 profile 关掉所有规则，保存后也不会丢
+func TestProfileRules(t *testing.T) {
+	cfg := NewDefault()
+	cfg.Rules = []RuleConfig{{Expr: "cpu > 80"}}
+	cfg.Profiles = map[string]Profile{
+		"loud":  {Rules: &[]RuleConfig{{Expr: "cpu > 10"}, {Expr: "mem > 10"}}},
+		"quiet": {Rules: &[]RuleConfig{}},
+		"plain": {},
+	}
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := Save(cfg, path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		profile string
+		want    int
+	}{{"loud", 2}, {"quiet", 0}, {"loud", 2}, {"plain", 1}, {"", 1}} {
+		loaded.Profile = tt.profile
+		l, err := Layer(loaded, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := len(l.Effective.Rules); got != tt.want {
+			t.Errorf("profile %q: %d rules, want %d", tt.profile, got, tt.want)
+		}
+		if _, fromProfile := l.Origins["rules"]; fromProfile != (tt.profile == "loud" || tt.profile == "quiet") {
+			t.Errorf("profile %q: rules origin = %v", tt.profile, l.Origins["rules"])
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 配置的来源，优先级从低到高
+const (
+	FromDefault = "default"
+	FromFile    = "file"
+	FromEnv     = "env"
+	FromFlag    = "flag"
+)
+
+// EnvPrefix 环境变量前缀，比如 OXPET_IMAGE_PATH、OXPET_DISPLAY_MODE
+const EnvPrefix = "OXPET_"
+
+// EnvConfig 指定配置文件路径的环境变量 (优先级低于 --config)
+const EnvConfig = EnvPrefix + "CONFIG"
+
+// Override 来自环境变量或命令行的一项覆盖，Value 是原始字符串 (列表类字段写成 JSON)
+type Override struct {
+	Key   string // 字段的 json 名，比如 "image_path"
+	Value string
+	From  string // FromEnv / FromFlag
+	Name  string // 环境变量名或参数名，报错和展示来源时使用
+}
+
+// Origin 一个字段的生效值来自哪里
+type Origin struct {
+	From string // FromDefault / FromFile / FromEnv / FromFlag
+	Name string // 文件路径、环境变量名或参数名
+}
+
+func (o Origin) String() string {
+	if o.Name == "" {
+		return o.From
+	}
+	return o.From + " " + o.Name
+}
+
+// Layered 分层叠加后的配置：默认值 < 配置文件 < 环境变量 < 命令行参数
+type Layered struct {
+	File      *Config           // 默认值 + 配置文件，写回文件时以它为底
+	Effective *Config           // 叠加覆盖项之后真正生效的配置
+	Origins   map[string]Origin // 字段名 -> 来源；没有记录的字段来自默认值
+}
+
+// Origin 返回字段的来源
+func (l *Layered) Origin(key string) Origin {
+	if o, ok := l.Origins[key]; ok {
+		return o
+	}
+	return Origin{From: FromDefault}
+}
+
+// Persistable 把运行中的配置转换成要写回文件的内容：
+//   - 被环境变量或命令行覆盖、而且运行中没有再改过的字段恢复成文件里的值，免得一次临时覆盖被写进共享的配置文件
+//   - 来自 profile 的字段不写到顶层；运行中改过的写回那个 profile
+func (l *Layered) Persistable(runtime *Config) *Config {
+	out := *runtime
+	rv, ev, fv, ov := reflect.ValueOf(runtime).Elem(), reflect.ValueOf(l.Effective).Elem(),
+		reflect.ValueOf(l.File).Elem(), reflect.ValueOf(&out).Elem()
+	out.Profiles = make(map[string]Profile, len(l.File.Profiles))
+	for name, p := range l.File.Profiles {
+		out.Profiles[name] = p
+	}
+	if len(out.Profiles) == 0 {
+		out.Profiles = nil
+	}
+
+	for _, f := range layerFields() {
+		o := l.Origins[f.key]
+		changed := !reflect.DeepEqual(rv.Field(f.index).Interface(), ev.Field(f.index).Interface())
+		switch o.From {
+		case FromEnv, FromFlag:
+			if !changed {
+				ov.Field(f.index).Set(fv.Field(f.index))
+			}
+		case FromProfile:
+			ov.Field(f.index).Set(fv.Field(f.index))
+			if changed {
+				p := out.Profiles[o.Name]
+				setProfileField(&p, f.key, rv.Field(f.index))
+				out.Profiles[o.Name] = p
+			}
+		}
+	}
+	return &out
+}
+
+// Layer 在 file 之上按顺序叠加覆盖项 (后面的优先)，并校验叠加后的结果
+func Layer(file *Config, overrides []Override) (*Layered, error) {
+	eff := *file
+	l := &Layered{File: file, Effective: &eff, Origins: map[string]Origin{}}
+
+	// 环境变量总是排在命令行参数前面，不管传进来的顺序
+	sorted := append([]Override(nil), overrides...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].From == FromEnv && sorted[j].From == FromFlag })
+
+	// 先叠加 profile (用哪个 profile 本身也可以被覆盖)，再叠加环境变量与命令行
+	profile := file.Profile
+	for _, o := range sorted {
+		if o.Key == "profile" {
+			profile = o.Value
+		}
+	}
+	keys, err := applyProfile(&eff, profile)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		l.Origins[key] = Origin{From: FromProfile, Name: profile}
+	}
+
+	for _, o := range sorted {
+		if err := o.apply(&eff); err != nil {
+			return nil, err
+		}
+		l.Origins[o.Key] = Origin{From: o.From, Name: o.Name}
+	}
+	eff.normalize()
+
+	var errs []Problem
+	for _, p := range Validate(&eff) {
+		if !p.Warning {
+			if o, ok := l.Origins[rootKey(p.Path)]; ok {
+				p.Msg += " (来自 " + o.String() + ")"
+			}
+			errs = append(errs, p)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, &ValidationError{Problems: errs}
+	}
+	return l, nil
+}
+
+// Resolve 读取配置文件并叠加覆盖项，同时标注每个字段的来源 (文件里写了的算 file)
+func Resolve(filename string, overrides []Override) (*Layered, error) {
+	file, err := Load(filename)
+	if err != nil {
+		return nil, err
+	}
+	l, err := Layer(file, overrides)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := map[string]json.RawMessage{}
+	if data, err := os.ReadFile(filename); err == nil && json.Unmarshal(data, &raw) == nil {
+		for key := range raw {
+			if _, overridden := l.Origins[key]; !overridden && knownFields()[key] {
+				l.Origins[key] = Origin{From: FromFile, Name: filename}
+			}
+		}
+	}
+	return l, nil
+}
+
+// EnvOverrides 从环境变量 (形如 "KEY=value") 中收集 OXPET_* 覆盖项；不认识的 OXPET_ 变量直接报错，避免拼错了却不生效
+func EnvOverrides(environ []string) ([]Override, error) {
+	byEnv := map[string]string{}
+	for _, f := range layerFields() {
+		byEnv[EnvName(f.key)] = f.key
+	}
+
+	var out []Override
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, EnvPrefix) || name == EnvConfig {
+			continue
+		}
+		key, ok := byEnv[name]
+		if !ok {
+			return nil, fmt.Errorf("不认识的环境变量 %s", name)
+		}
+		out = append(out, Override{Key: key, Value: value, From: FromEnv, Name: name})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out, nil
+}
+
+// RegisterFlags 为每个字段注册一个命令行参数 (比如 --image-path、--show-color、--display-mode)，
+// 返回的函数在 fs.Parse 之后调用，得到实际出现的覆盖项
+func RegisterFlags(fs *flag.FlagSet) func() []Override {
+	var out []Override
+	for _, f := range layerFields() {
+		key, name := f.key, FlagName(f.key)
+		record := func(v string) error {
+			out = append(out, Override{Key: key, Value: v, From: FromFlag, Name: "--" + name})
+			return nil
+		}
+		usage := "覆盖配置里的 " + key
+		switch f.kind {
+		case reflect.Bool:
+			fs.BoolFunc(name, usage+" (true/false)", record)
+		case reflect.Slice, reflect.Map:
+			fs.Func(name, usage+" (JSON)", record)
+		default:
+			fs.Func(name, usage, record)
+		}
+	}
+	return func() []Override { return out }
+}
+
+// ConfigPath 决定配置文件路径：--config 优先，其次 OXPET_CONFIG，最后是 fallback
+func ConfigPath(flagValue, fallback string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if env := os.Getenv(EnvConfig); env != "" {
+		return env
+	}
+	return fallback
+}
+
+// EnvName 字段对应的环境变量名
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(key)
+}
+
+// FlagName 字段对应的命令行参数名 (不含 "--")
+func FlagName(key string) string {
+	return strings.ReplaceAll(key, "_", "-")
+}
+
+// Keys 返回可以被覆盖的字段名，按结构体顺序
+func Keys() []string {
+	fields := layerFields()
+	keys := make([]string, len(fields))
+	for i, f := range fields {
+		keys[i] = f.key
+	}
+	return keys
+}
+
+// Value 返回字段当前值的 JSON 表示，用于展示
+func (cfg *Config) Value(key string) string {
+	for _, f := range layerFields() {
+		if f.key == key {
+			var buf bytes.Buffer
+			enc := json.NewEncoder(&buf)
+			enc.SetEscapeHTML(false) // 规则表达式里的 > < 原样显示
+			enc.Encode(reflect.ValueOf(cfg).Elem().Field(f.index).Interface())
+			return strings.TrimSpace(buf.String())
+		}
+	}
+	return ""
+}
+
+// apply 把字符串值按字段类型写入 cfg
+func (o Override) apply(cfg *Config) error {
+	for _, f := range layerFields() {
+		if f.key != o.Key {
+			continue
+		}
+		v := reflect.ValueOf(cfg).Elem().Field(f.index)
+		switch f.kind {
+		case reflect.String:
+			v.SetString(o.Value)
+		case reflect.Bool:
+			b, err := strconv.ParseBool(o.Value)
+			if err != nil {
+				return fmt.Errorf("%s: 应该是 true 或 false，而不是 %q", o.Name, o.Value)
+			}
+			v.SetBool(b)
+		case reflect.Int:
+			n, err := strconv.Atoi(o.Value)
+			if err != nil {
+				return fmt.Errorf("%s: 应该是整数，而不是 %q", o.Name, o.Value)
+			}
+			v.SetInt(int64(n))
+		case reflect.Float64:
+			n, err := strconv.ParseFloat(o.Value, 64)
+			if err != nil {
+				return fmt.Errorf("%s: 应该是数字，而不是 %q", o.Name, o.Value)
+			}
+			v.SetFloat(n)
+		case reflect.Slice, reflect.Map:
+			ptr := reflect.New(v.Type())
+			if err := json.Unmarshal([]byte(o.Value), ptr.Interface()); err != nil {
+				return fmt.Errorf("%s: 应该是 JSON: %v", o.Name, err)
+			}
+			v.Set(ptr.Elem())
+		default:
+			return fmt.Errorf("%s: 不支持覆盖", o.Name)
+		}
+		return nil
+	}
+	return fmt.Errorf("%s: 未知的配置项 %q", o.Name, o.Key)
+}
+
+// layerField 可以分层覆盖的字段 (除 version 以外的全部字段)
+type layerField struct {
+	key   string
+	index int
+	kind  reflect.Kind
+}
+
+func layerFields() []layerField {
+	var out []layerField
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" || name == "version" {
+			continue
+		}
+		out = append(out, layerField{key: name, index: i, kind: t.Field(i).Type.Kind()})
+	}
+	return out
+}
+
+// rootKey 从 "rules[0].expr" 这样的路径中取出顶层字段名
+func rootKey(path string) string {
+	if i := strings.IndexAny(path, ".["); i >= 0 {
+		return path[:i]
+	}
+	return path
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// CurrentVersion 当前配置格式的版本号
+//
+//	0: 最早的格式，没有 version 字段
+//	1: 加入 version 与 display_mode，所有运行时设置都会完整保存
+const CurrentVersion = 1
+
+// migrations[i] 把版本 i 的配置升级到版本 i+1，直接修改原始 JSON
+var migrations = []func(raw map[string]json.RawMessage) error{
+	migrateV0,
+}
+
+// Migrate 把原始 JSON 逐级升级到 CurrentVersion，返回文件原来的版本号；
+// 比当前程序更新的版本不做改动，交给解析时尽量读取
+func Migrate(raw map[string]json.RawMessage) (int, error) {
+	from := 0
+	if v, ok := raw["version"]; ok {
+		if err := json.Unmarshal(v, &from); err != nil || from < 0 {
+			return 0, fmt.Errorf("无效的配置版本: %s", v)
+		}
+	}
+	for v := from; v < CurrentVersion; v++ {
+		if err := migrations[v](raw); err != nil {
+			return from, fmt.Errorf("从版本 %d 升级失败: %w", v, err)
+		}
+		raw["version"] = json.RawMessage(fmt.Sprint(v + 1))
+	}
+	return from, nil
+}
+
+// migrateV0 旧版保存时总是把 show_glitch / show_animation 写成 false (并不是用户的选择)，
+// 升级时去掉这两个 false，让它们回到默认值；display_mode 从没保存过，缺省即可
+func migrateV0(raw map[string]json.RawMessage) error {
+	for _, key := range []string{"show_glitch", "show_animation"} {
+		var on bool
+		if v, ok := raw[key]; ok && json.Unmarshal(v, &on) == nil && !on {
+			delete(raw, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// v0File 旧版程序保存的配置：没有 version，show_glitch / show_animation 总是被写成 false
+const v0File = `{
+  "image_path": "assets/cat.png",
+  "show_color": false,
+  "show_glitch": false,
+  "show_animation": false,
+  "show_monitor": true
+}`
+
+func TestLoadUpgradesV0(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(v0File), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Version != CurrentVersion || cfg.ShowColor || !cfg.ShowMonitor || cfg.ImagePath != "assets/cat.png" {
+		t.Errorf("user settings not kept: %+v", cfg)
+	}
+	if !cfg.ShowGlitch || !cfg.ShowAnimation {
+		t.Errorf("show_glitch, show_animation = %v, %v; want restored to true", cfg.ShowGlitch, cfg.ShowAnimation)
+	}
+	if cfg.DisplayMode != 0 {
+		t.Errorf("display_mode = %d, want default 0", cfg.DisplayMode)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		from     int
+		wantKeys map[string]string // 升级后应有的字段，值为 "" 表示应被删掉
+		err      bool
+	}{
+		{
+			name:     "v0 false flags dropped",
+			in:       `{"show_glitch": false, "show_animation": false, "show_color": false}`,
+			from:     0,
+			wantKeys: map[string]string{"version": "1", "show_glitch": "", "show_animation": "", "show_color": "false"},
+		},
+		{
+			name:     "v0 true flags kept",
+			in:       `{"show_glitch": true}`,
+			from:     0,
+			wantKeys: map[string]string{"version": "1", "show_glitch": "true"},
+		},
+		{
+			name:     "current version untouched",
+			in:       `{"version": 1, "show_glitch": false}`,
+			from:     1,
+			wantKeys: map[string]string{"version": "1", "show_glitch": "false"},
+		},
+		{
+			name:     "newer version untouched",
+			in:       `{"version": 99, "show_glitch": false}`,
+			from:     99,
+			wantKeys: map[string]string{"version": "99", "show_glitch": "false"},
+		},
+		{name: "negative version", in: `{"version": -1}`, err: true},
+		{name: "non-numeric version", in: `{"version": "one"}`, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := map[string]json.RawMessage{}
+			if err := json.Unmarshal([]byte(tt.in), &raw); err != nil {
+				t.Fatal(err)
+			}
+			from, err := Migrate(raw)
+			if (err != nil) != tt.err {
+				t.Fatalf("error = %v, want error %v", err, tt.err)
+			}
+			if tt.err {
+				return
+			}
+			if from != tt.from {
+				t.Errorf("from = %d, want %d", from, tt.from)
+			}
+			for k, want := range tt.wantKeys {
+				got, ok := raw[k]
+				switch {
+				case want == "" && ok:
+					t.Errorf("%s = %s, want removed", k, got)
+				case want != "" && string(got) != want:
+					t.Errorf("%s = %s, want %s", k, got, want)
+				}
+			}
+		})
+	}
+}
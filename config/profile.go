@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// FromProfile 字段的值来自当前 profile
+const FromProfile = "profile"
+
+// Profile 一组可以整体切换的设置，比如 "work" (单色、开 HUD)、"focus" (迷你模式、不乱码)；
+// 没写的字段 (nil) 沿用顶层配置
+type Profile struct {
+	ImagePath     *string       `json:"image_path,omitempty"`
+	ShowColor     *bool         `json:"show_color,omitempty"`
+	ShowGlitch    *bool         `json:"show_glitch,omitempty"`
+	ShowAnimation *bool         `json:"show_animation,omitempty"`
+	ShowMonitor   *bool         `json:"show_monitor,omitempty"`
+	DisplayMode   *int          `json:"display_mode,omitempty"`
+	Ramp          *string       `json:"ramp,omitempty"`
+	Theme         *string       `json:"theme,omitempty"`
+	Rules         *[]RuleConfig `json:"rules,omitempty"` // 整体替换顶层规则，写成 [] 表示这个 profile 下不用任何规则
+}
+
+// ProfileNames 返回所有 profile 的名字，按字母排序
+func (cfg *Config) ProfileNames() []string {
+	names := make([]string, 0, len(cfg.Profiles))
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyProfile 把名为 name 的 profile 叠加到 cfg 上，返回被它设置的字段名；name 为空时什么都不做
+func applyProfile(cfg *Config, name string) ([]string, error) {
+	if name == "" {
+		return nil, nil
+	}
+	p, ok := cfg.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile %q 不存在，可选: %s", name, strings.Join(cfg.ProfileNames(), ", "))
+	}
+
+	var keys []string
+	cv := reflect.ValueOf(cfg).Elem()
+	eachProfileField(&p, func(key string, pf reflect.Value, index int) {
+		if pf.IsNil() {
+			return
+		}
+		cv.Field(index).Set(pf.Elem())
+		keys = append(keys, key)
+	})
+	return keys, nil
+}
+
+// setProfileField 把 value 写进 profile 的 key 字段 (保存运行中对 profile 设置的修改)
+func setProfileField(p *Profile, key string, value reflect.Value) {
+	eachProfileField(p, func(k string, pf reflect.Value, _ int) {
+		if k != key {
+			return
+		}
+		ptr := reflect.New(value.Type())
+		ptr.Elem().Set(value)
+		pf.Set(ptr)
+	})
+}
+
+// eachProfileField 遍历 Profile 的字段，同时给出它在 Config 里对应字段的下标 (按 json 名对应)
+func eachProfileField(p *Profile, fn func(key string, pf reflect.Value, configIndex int)) {
+	index := map[string]int{}
+	for _, f := range layerFields() {
+		index[f.key] = f.index
+	}
+	pv := reflect.ValueOf(p).Elem()
+	pt := pv.Type()
+	for i := 0; i < pt.NumField(); i++ {
+		key, _, _ := strings.Cut(pt.Field(i).Tag.Get("json"), ",")
+		if ci, ok := index[key]; ok {
+			fn(key, pv.Field(i), ci)
+		}
+	}
+}
@@ -0,0 +1,571 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Validator config 自己判断不了的检查：规则表达式、状态名与主题分别由 rules、entity、theme 包提供，
+// 由 main 在启动时通过 SetValidator 传进来，config 不依赖这些包
+type Validator struct {
+	Expr  func(expr string) error  // 规则表达式，比如 "cpu > 85 for 10s"
+	State func(state string) error // 状态名，空字符串不会传进来
+	Theme func(name string) error  // 主题名，找不到时返回错误
+}
+
+// checks 当前使用的检查，由 SetValidator 设置
+var checks Validator
+
+// SetValidator 设置 Check、Validate 与 Load 使用的检查；要在读取任何配置之前调用
+func SetValidator(v Validator) {
+	checks = v
+}
+
+// Missing 返回没有提供的检查，全部提供时为空
+func (v Validator) Missing() []string {
+	var missing []string
+	if v.Expr == nil {
+		missing = append(missing, "规则表达式检查")
+	}
+	if v.State == nil {
+		missing = append(missing, "状态名检查")
+	}
+	if v.Theme == nil {
+		missing = append(missing, "主题检查")
+	}
+	return missing
+}
+
+// CheckLoopback 检查地址是否只监听本机：控制端点能让宠物显示任意内容、触发自动退出，不能暴露到网络上
+func CheckLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("控制端点只能绑定本机地址 (127.0.0.1、::1 或 localhost)，而不是 %q", addr)
+}
+
+// Problem 配置里的一处问题，Line/Col 从 1 开始，为 0 表示位置未知
+type Problem struct {
+	Path    string // 出问题的字段，比如 "rules[0].expr"
+	Line    int
+	Col     int
+	Msg     string
+	Warning bool // 警告不影响加载 (比如不认识的字段)，只是提醒
+}
+
+func (p Problem) String() string {
+	var b strings.Builder
+	if p.Line > 0 {
+		fmt.Fprintf(&b, "%d:%d: ", p.Line, p.Col)
+	}
+	if p.Warning {
+		b.WriteString("警告: ")
+	}
+	if p.Path != "" {
+		b.WriteString(p.Path + ": ")
+	}
+	b.WriteString(p.Msg)
+	return b.String()
+}
+
+// ValidationError 配置文件里的错误 (不含警告)
+type ValidationError struct {
+	File     string // 文件名，由 Load 填写
+	Problems []Problem
+}
+
+// In 带上文件名，格式与编译器一致："config.json:12:5: display_mode: ..."
+func (p Problem) In(file string) string {
+	switch {
+	case file == "":
+		return p.String()
+	case p.Line > 0:
+		return file + ":" + p.String()
+	default:
+		return file + ": " + p.String()
+	}
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		lines[i] = p.In(e.File)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Check 解析并校验一份配置：语法错误、类型错误、取值范围和枚举值都会带上行列号；
+// 有错误时返回的 *Config 为 nil，只有警告时照常返回
+func Check(data []byte) (*Config, []Problem) {
+	pos := positions(data)
+	var probs []Problem
+	add := func(warning bool, path, format string, args ...any) {
+		p := Problem{Path: path, Msg: fmt.Sprintf(format, args...), Warning: warning}
+		p.Line, p.Col = lineCol(data, lookup(pos, path))
+		probs = append(probs, p)
+	}
+
+	// 1. 语法与类型：先按原文解析一次，这样报错的位置和用户看到的文件一致
+	var probe Config
+	if err := json.Unmarshal(data, &probe); err != nil {
+		var syntax *json.SyntaxError
+		var typ *json.UnmarshalTypeError
+		p := Problem{Msg: err.Error()}
+		switch {
+		case errors.As(err, &syntax):
+			p.Msg = "JSON 语法错误: " + syntax.Error()
+			p.Line, p.Col = lineCol(data, syntax.Offset)
+		case errors.As(err, &typ):
+			p.Path = typeErrorPath(typ)
+			p.Msg = fmt.Sprintf("应该是 %s，不能是 %s", typ.Type, typ.Value)
+			p.Line, p.Col = lineCol(data, typ.Offset) // Offset 指向值的末尾，能找到值的开头时用开头
+			if off, ok := pos[p.Path]; ok {
+				p.Line, p.Col = lineCol(data, off)
+			}
+		}
+		return nil, append(probs, p)
+	}
+
+	// 2. 升级到当前版本再解析出真正使用的配置
+	cfg, err := decode(data)
+	if err != nil {
+		add(false, "version", "%v", err)
+		return nil, probs
+	}
+
+	// 3. 不认识的字段只警告 (保存时会原样保留)，多半是拼写错误
+	var raw any
+	json.Unmarshal(data, &raw)
+	for _, path := range unknownPaths(raw, reflect.TypeOf(Config{}), "") {
+		add(true, path, "不认识的字段，将被忽略")
+	}
+
+	// 4. 取值范围与枚举；没提供的检查要说出来，而不是悄悄跳过
+	for _, name := range checks.Missing() {
+		add(true, "", "没有%s，相关字段未经检查", name)
+	}
+	probs = append(probs, validate(cfg, pos, data)...)
+	sort.SliceStable(probs, func(i, j int) bool {
+		if probs[i].Line != probs[j].Line {
+			return probs[i].Line < probs[j].Line
+		}
+		return probs[i].Col < probs[j].Col
+	})
+
+	for _, p := range probs {
+		if !p.Warning {
+			return nil, probs
+		}
+	}
+	return cfg, probs
+}
+
+// Validate 检查一份已经解析好的配置 (比如叠加了环境变量之后的)，问题没有行列号
+func Validate(cfg *Config) []Problem {
+	return validate(cfg, nil, nil)
+}
+
+// validate 逐个字段检查取值
+func validate(cfg *Config, pos map[string]int64, data []byte) []Problem {
+	var probs []Problem
+	add := func(warning bool, path, format string, args ...any) {
+		p := Problem{Path: path, Msg: fmt.Sprintf(format, args...), Warning: warning}
+		p.Line, p.Col = lineCol(data, lookup(pos, path))
+		probs = append(probs, p)
+	}
+	errorf := func(path, format string, args ...any) { add(false, path, format, args...) }
+
+	if cfg.Version > CurrentVersion {
+		add(true, "version", "版本 %d 比本程序支持的 %d 新，部分设置可能不生效", cfg.Version, CurrentVersion)
+	}
+	if cfg.DisplayMode < 0 || cfg.DisplayMode > 2 {
+		errorf("display_mode", "必须是 0、1 或 2，而不是 %d", cfg.DisplayMode)
+	}
+	if cfg.Ramp != "" {
+		if utf8.RuneCountInString(cfg.Ramp) < 2 {
+			errorf("ramp", "至少需要两个字符")
+		} else if !isASCII(cfg.Ramp) {
+			add(true, "ramp", "包含非 ASCII 字符，字体里可能没有对应的字形")
+		}
+	}
+	// 主题在另一个文件里，找不到或写错了只是退回默认配色，不影响加载
+	if cfg.Theme != "" && checks.Theme != nil {
+		if err := checks.Theme(cfg.Theme); err != nil {
+			add(true, "theme", "%v，将使用默认主题", err)
+		}
+	}
+
+	checkAddr(errorf, "metrics_addr", cfg.MetricsAddr)
+	checkAddr(errorf, "control_addr", cfg.ControlAddr)
+	if _, _, err := net.SplitHostPort(cfg.ControlAddr); err == nil {
+		if err := CheckLoopback(cfg.ControlAddr); err != nil {
+			errorf("control_addr", "%v", err)
+		}
+	}
+	if cfg.RemoteURL != "" {
+		if u, err := url.Parse(cfg.RemoteURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errorf("remote_url", "应该是 http:// 或 https:// 开头的地址，比如 \"http://buildbox:9465\"")
+		}
+	}
+	checkEnum(errorf, "remote_mode", cfg.RemoteMode, "", "json", "sse")
+	if cfg.CgroupRoot != "" && !filepath.IsAbs(cfg.CgroupRoot) {
+		errorf("cgroup_root", "必须是绝对路径")
+	}
+	if cfg.LowBattery < 0 || cfg.LowBattery > 100 {
+		errorf("low_battery", "必须在 0 到 100 之间")
+	}
+	if cfg.HotTemp < 0 || cfg.HotTemp > 150 {
+		errorf("hot_temp", "必须在 0 到 150 °C 之间")
+	}
+	if cfg.Friction < 0 {
+		errorf("friction", "不能是负数")
+	}
+	if cfg.Restitution < 0 || cfg.Restitution > 1 {
+		errorf("restitution", "必须在 0 到 1 之间")
+	}
+	if cfg.MaxSpeed < 0 {
+		errorf("max_speed", "不能是负数")
+	}
+	if cfg.Floor < 0 {
+		errorf("floor", "不能是负数")
+	}
+
+	names := map[string]bool{}
+	for i, r := range cfg.Rules {
+		p := fmt.Sprintf("rules[%d]", i)
+		if strings.TrimSpace(r.Expr) == "" {
+			errorf(p+".expr", "不能为空")
+		} else if checks.Expr != nil {
+			if err := checks.Expr(r.Expr); err != nil {
+				errorf(p+".expr", "%v", err)
+			}
+		}
+		name := r.Name
+		if name == "" {
+			name = r.Expr
+		}
+		if names[name] {
+			add(true, p+".name", "规则名 %q 重复，告警状态会互相覆盖", name)
+		}
+		names[name] = true
+		checkState(errorf, p+".state", r.State)
+		checkColor(errorf, p+".color", r.Color)
+		checkDuration(errorf, p+".cooldown", r.Cooldown, true)
+	}
+
+	metrics := map[string]bool{}
+	for i, m := range cfg.CustomMetrics {
+		p := fmt.Sprintf("custom_metrics[%d]", i)
+		switch {
+		case m.Name == "":
+			errorf(p+".name", "不能为空")
+		case metrics[m.Name]:
+			errorf(p+".name", "指标名 %q 重复", m.Name)
+		}
+		metrics[m.Name] = true
+		checkEnum(errorf, p+".type", m.Type, "command", "file", "socket")
+		if m.Type == "command" && m.Command == "" {
+			errorf(p+".command", "type 为 command 时不能为空")
+		}
+		if (m.Type == "file" || m.Type == "socket") && m.Path == "" {
+			errorf(p+".path", "type 为 %s 时不能为空", m.Type)
+		}
+		checkDuration(errorf, p+".interval", m.Interval, false)
+		checkDuration(errorf, p+".timeout", m.Timeout, false)
+		if m.Max < 0 {
+			errorf(p+".max", "不能是负数")
+		}
+	}
+
+	for i, lw := range cfg.LogWatches {
+		p := fmt.Sprintf("log_watches[%d]", i)
+		if lw.Path == "" {
+			errorf(p+".path", "不能为空")
+		}
+		if len(lw.Rules) == 0 {
+			add(true, p+".rules", "没有任何规则，这个文件不会触发反应")
+		}
+		for j, r := range lw.Rules {
+			rp := fmt.Sprintf("%s.rules[%d]", p, j)
+			checkRegexp(errorf, rp+".pattern", r.Pattern)
+			checkState(errorf, rp+".state", r.State)
+			checkColor(errorf, rp+".color", r.Color)
+			checkDuration(errorf, rp+".duration", r.Duration, true)
+		}
+	}
+
+	for i, pw := range cfg.ProcessWatches {
+		p := fmt.Sprintf("process_watches[%d]", i)
+		checkRegexp(errorf, p+".pattern", pw.Pattern)
+		checkDuration(errorf, p+".busy_after", pw.BusyAfter, true)
+	}
+
+	// profile：叠加到顶层配置上再检查，只报告 profile 自己设置的字段
+	if cfg.Profile != "" {
+		if _, ok := cfg.Profiles[cfg.Profile]; !ok {
+			errorf("profile", "profile %q 不存在", cfg.Profile)
+		}
+	}
+	for _, name := range cfg.ProfileNames() {
+		if name == "" {
+			errorf("profiles", "profile 名不能为空")
+			continue
+		}
+		merged := *cfg
+		keys, _ := applyProfile(&merged, name)
+		merged.Profile, merged.Profiles = "", nil // 只检查叠加后的普通字段
+		set := map[string]bool{}
+		for _, k := range keys {
+			set[k] = true
+		}
+		for _, pr := range validate(&merged, nil, nil) {
+			if set[rootKey(pr.Path)] {
+				path := "profiles." + name + "." + pr.Path
+				add(pr.Warning, path, "%s", pr.Msg)
+			}
+		}
+	}
+	return probs
+}
+
+type errorFunc func(path, format string, args ...any)
+
+func checkAddr(errorf errorFunc, path, addr string) {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		errorf(path, "应该是 \"主机:端口\"，比如 \"127.0.0.1:9464\"")
+		return
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		errorf(path, "无效的端口 %q", port)
+	}
+}
+
+func checkEnum(errorf errorFunc, path, v string, allowed ...string) {
+	for _, a := range allowed {
+		if v == a {
+			return
+		}
+	}
+	quoted := make([]string, 0, len(allowed))
+	for _, a := range allowed {
+		if a != "" {
+			quoted = append(quoted, strconv.Quote(a))
+		}
+	}
+	errorf(path, "%q 无效，可选值: %s", v, strings.Join(quoted, "、"))
+}
+
+func checkState(errorf errorFunc, path, state string) {
+	if state == "" || checks.State == nil {
+		return
+	}
+	if err := checks.State(state); err != nil {
+		errorf(path, "%v", err)
+	}
+}
+
+var colorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)
+
+func checkColor(errorf errorFunc, path, c string) {
+	if c != "" && !colorPattern.MatchString(c) {
+		errorf(path, "颜色应该写成 \"#rrggbb\" 或 \"#rrggbbaa\"，而不是 %q", c)
+	}
+}
+
+// checkDuration 检查 "10s"、"1m" 这样的时长；allowZero 为 false 时必须大于 0
+func checkDuration(errorf errorFunc, path, s string, allowZero bool) {
+	if s == "" {
+		return
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		errorf(path, "无效的时长 %q (比如 \"10s\"、\"1m\")", s)
+		return
+	}
+	if d < 0 || (d == 0 && !allowZero) {
+		errorf(path, "时长必须大于 0")
+	}
+}
+
+func checkRegexp(errorf errorFunc, path, pattern string) {
+	if pattern == "" {
+		errorf(path, "不能为空")
+		return
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		errorf(path, "正则有误: %v", err)
+	}
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// unknownPaths 对照结构体的 json 标签，找出 raw 里所有不认识的字段 (包括嵌套的)
+func unknownPaths(raw any, t reflect.Type, prefix string) []string {
+	var out []string
+	switch t.Kind() {
+	case reflect.Slice:
+		if arr, ok := raw.([]any); ok {
+			for i, v := range arr {
+				out = append(out, unknownPaths(v, t.Elem(), fmt.Sprintf("%s[%d]", prefix, i))...)
+			}
+		}
+	case reflect.Map:
+		if obj, ok := raw.(map[string]any); ok {
+			for k, v := range obj {
+				out = append(out, unknownPaths(v, t.Elem(), prefix+"."+k)...)
+			}
+		}
+	case reflect.Struct:
+		obj, ok := raw.(map[string]any)
+		if !ok {
+			return nil
+		}
+		fields := map[string]reflect.Type{}
+		for i := 0; i < t.NumField(); i++ {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+			if name != "" && name != "-" {
+				fields[name] = t.Field(i).Type
+			}
+		}
+		for key, v := range obj {
+			path := key
+			if prefix != "" {
+				path = prefix + "." + key
+			}
+			ft, known := fields[key]
+			if !known {
+				out = append(out, path)
+				continue
+			}
+			out = append(out, unknownPaths(v, ft, path)...)
+		}
+	}
+	return out
+}
+
+// positions 扫描一遍 JSON，记录每个值 (按 "a.b[0].c" 形式的路径) 在文件中的偏移
+func positions(data []byte) map[string]int64 {
+	pos := map[string]int64{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	var walk func(path string) error
+	walk = func(path string) error {
+		start := skipSeparators(data, dec.InputOffset())
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		pos[path] = start
+
+		switch tok {
+		case json.Delim('{'):
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				child := fmt.Sprint(key)
+				if path != "" {
+					child = path + "." + child
+				}
+				if err := walk(child); err != nil {
+					return err
+				}
+			}
+			_, err = dec.Token()
+		case json.Delim('['):
+			for i := 0; dec.More(); i++ {
+				if err := walk(fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+			_, err = dec.Token()
+		}
+		return err
+	}
+	walk("")
+	return pos
+}
+
+// skipSeparators 跳过值前面的空白、冒号和逗号，让偏移指向值本身
+func skipSeparators(data []byte, off int64) int64 {
+	for off < int64(len(data)) {
+		switch data[off] {
+		case ' ', '\t', '\r', '\n', ':', ',':
+			off++
+		default:
+			return off
+		}
+	}
+	return off
+}
+
+// lookup 找到路径对应的偏移；字段本身不在文件里 (比如缺省值) 时退到最近的上一级
+func lookup(pos map[string]int64, path string) int64 {
+	for {
+		if off, ok := pos[path]; ok {
+			return off
+		}
+		i := strings.LastIndexAny(path, ".[")
+		if i < 0 {
+			return -1
+		}
+		path = path[:i]
+	}
+}
+
+// lineCol 把字节偏移换算成行号与列号 (都从 1 开始)，偏移无效时返回 0, 0
+func lineCol(data []byte, off int64) (int, int) {
+	if off < 0 || off > int64(len(data)) {
+		return 0, 0
+	}
+	before := data[:off]
+	line := bytes.Count(before, []byte("\n")) + 1
+	lastNL := bytes.LastIndexByte(before, '\n')
+	col := utf8.RuneCount(before[lastNL+1:]) + 1
+	return line, col
+}
+
+// typeErrorPath 把 encoding/json 的字段路径 ("rules.0.expr" 之类) 转成 "rules[0].expr"
+func typeErrorPath(e *json.UnmarshalTypeError) string {
+	parts := strings.Split(e.Field, ".")
+	var b strings.Builder
+	for _, p := range parts {
+		if _, err := strconv.Atoi(p); err == nil {
+			b.WriteString("[" + p + "]")
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString(".")
+		}
+		b.WriteString(p)
+	}
+	return b.String()
+}
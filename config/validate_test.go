@@ -0,0 +1,106 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeValidator 只认识 "cpu > N" 形式的表达式、"stressed" 状态和 "amber" 主题
+func fakeValidator() Validator {
+	return Validator{
+		Expr: func(expr string) error {
+			if !strings.HasPrefix(expr, "cpu > ") {
+				return errors.New("看不懂的表达式")
+			}
+			return nil
+		},
+		State: func(state string) error {
+			if state != "stressed" {
+				return errors.New("未知的状态")
+			}
+			return nil
+		},
+		Theme: func(name string) error {
+			if name != "amber" {
+				return errors.New("找不到主题")
+			}
+			return nil
+		},
+	}
+}
+
+// problemAt 找到 path 上的问题
+func problemAt(probs []Problem, path string) (Problem, bool) {
+	for _, p := range probs {
+		if p.Path == path {
+			return p, true
+		}
+	}
+	return Problem{}, false
+}
+
+func TestValidatorChecks(t *testing.T) {
+	data := []byte(`{
+  "version": 2,
+  "theme": "neon",
+  "rules": [
+    {"expr": "cpu > 90", "state": "stressed"},
+    {"expr": "gpu ~ 3", "state": "angry"}
+  ]
+}`)
+
+	SetValidator(fakeValidator())
+	t.Cleanup(func() { SetValidator(Validator{}) })
+	cfg, probs := Check(data)
+	if cfg != nil {
+		t.Fatal("Check accepted an invalid rule expression")
+	}
+	for path, line := range map[string]int{"theme": 3, "rules[1].expr": 6, "rules[1].state": 6} {
+		p, ok := problemAt(probs, path)
+		if !ok || p.Line != line {
+			t.Errorf("%s: got %+v, want a problem on line %d", path, p, line)
+		}
+	}
+	if p, _ := problemAt(probs, "theme"); !p.Warning {
+		t.Error("an unknown theme should only be a warning")
+	}
+	if _, ok := problemAt(probs, "rules[0].expr"); ok {
+		t.Error("valid rule reported")
+	}
+
+	// 没有提供检查时这些字段放行，但要警告出来
+	SetValidator(Validator{})
+	cfg, probs = Check(data)
+	if cfg == nil {
+		t.Fatalf("Check without a validator failed: %v", probs)
+	}
+	missing := 0
+	for _, p := range probs {
+		if p.Warning && strings.Contains(p.Msg, "未经检查") {
+			missing++
+		}
+	}
+	if missing != 3 {
+		t.Errorf("got %d missing-check warnings, want 3: %v", missing, probs)
+	}
+}
+
+func TestCheckLoopback(t *testing.T) {
+	for _, addr := range []string{"127.0.0.1:9470", "[::1]:9470", "localhost:0", "127.1.2.3:80"} {
+		if err := CheckLoopback(addr); err != nil {
+			t.Errorf("CheckLoopback(%q) = %v", addr, err)
+		}
+	}
+	for _, addr := range []string{"0.0.0.0:9470", ":9470", "192.168.1.5:9470", "example.com:80", "nonsense"} {
+		if err := CheckLoopback(addr); err == nil {
+			t.Errorf("CheckLoopback(%q) accepted", addr)
+		}
+	}
+
+	cfg := NewDefault()
+	cfg.ControlAddr = "0.0.0.0:9470"
+	if _, ok := problemAt(Validate(cfg), "control_addr"); !ok {
+		t.Error("Validate accepted a non-loopback control_addr")
+	}
+}
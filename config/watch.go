@@ -0,0 +1,102 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultWatchInterval 检查配置文件是否被修改的间隔
+const DefaultWatchInterval = time.Second
+
+// Watcher 轮询配置文件的修改时间，内容变化后重新解析并回调
+//
+// 为了不读到编辑器写了一半的文件，修改时间要在连续两次检查中保持不变才会重新读取
+type Watcher struct {
+	path     string
+	interval time.Duration
+	onChange func(*Config, error)
+
+	lastStat fileStamp // 上一次检查看到的状态
+	applied  fileStamp // 已经处理过的状态
+	lastData []byte    // 最近一次成功解析的内容，内容没变 (比如自己保存的) 就不回调
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+type fileStamp struct {
+	mod  time.Time
+	size int64
+}
+
+// NewWatcher 创建配置监听器；onChange 在监听器自己的协程里调用，解析失败时 cfg 为 nil
+func NewWatcher(path string, interval time.Duration, onChange func(*Config, error)) *Watcher {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	return &Watcher{path: path, interval: interval, onChange: onChange, stop: make(chan struct{})}
+}
+
+// Start 记下文件当前的状态并开始轮询，启动时不会回调
+func (w *Watcher) Start() {
+	w.applied, _ = stampOf(w.path)
+	w.lastStat = w.applied
+	w.lastData, _ = os.ReadFile(w.path)
+
+	go func() {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+				w.check()
+			}
+		}
+	}()
+}
+
+// Stop 停止轮询
+func (w *Watcher) Stop() {
+	w.stopOnce.Do(func() { close(w.stop) })
+}
+
+func (w *Watcher) check() {
+	st, ok := stampOf(w.path)
+	if !ok {
+		return // 文件暂时不存在 (比如编辑器先删后写)，等它回来
+	}
+	settled := st == w.lastStat
+	w.lastStat = st
+	if !settled || st == w.applied {
+		return
+	}
+	w.applied = st
+
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		w.onChange(nil, err)
+		return
+	}
+	if bytes.Equal(data, w.lastData) {
+		return
+	}
+	w.lastData = data
+
+	cfg, err := Parse(data)
+	if err != nil {
+		w.lastData = nil // 改回原样时也要通知，好让调用方清掉错误
+	}
+	w.onChange(cfg, err)
+}
+
+func stampOf(path string) (fileStamp, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, false
+	}
+	return fileStamp{mod: info.ModTime(), size: info.Size()}, true
+}
@@ -0,0 +1,96 @@
+package ascii
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+
+	"0xPet/internal/entity"
+)
+
+// cacheVersion 转换算法或字符集变化时加一，让旧缓存自动失效
+const cacheVersion = 1
+
+// Cache 把转换结果按 "源文件内容 + 目标宽度 + 字符集" 存到磁盘上，同一张图下次启动时不用重新计算
+type Cache struct {
+	Dir string // 缓存目录，为空时不缓存
+}
+
+// cachedCell 缓存里的单个字符，颜色按 16 位 RGBA 保存
+type cachedCell struct {
+	C    string    `json:"c"`
+	RGBA [4]uint16 `json:"rgba"`
+}
+
+type cachedResult struct {
+	Lines []string       `json:"lines"`
+	Grid  [][]cachedCell `json:"grid"`
+}
+
+// SourceKey 根据图片文件的原始字节计算缓存键
+func SourceKey(src []byte) string {
+	sum := sha256.Sum256(src)
+	return hex.EncodeToString(sum[:16])
+}
+
+// Convert 与 ConvertRamp 相同，但会先查缓存；key 为空 (比如图片不是来自文件) 时直接转换
+func (c Cache) Convert(key string, img image.Image, targetWidth int, ramp string) ([]string, [][]entity.CharData) {
+	if c.Dir == "" || key == "" {
+		return ConvertRamp(img, targetWidth, ramp)
+	}
+	if ramp == "" {
+		ramp = DefaultRamp
+	}
+	rampSum := sha256.Sum256([]byte(ramp))
+	name := fmt.Sprintf("%s-w%d-r%s-v%d.json", key, targetWidth, hex.EncodeToString(rampSum[:4]), cacheVersion)
+	path := filepath.Join(c.Dir, name)
+
+	// 1. 命中缓存直接返回；缓存损坏就当没有
+	if data, err := os.ReadFile(path); err == nil {
+		var res cachedResult
+		if json.Unmarshal(data, &res) == nil {
+			return res.Lines, fromCached(res.Grid)
+		}
+	}
+
+	// 2. 重新计算并写入缓存 (写失败不影响结果)
+	lines, grid := ConvertRamp(img, targetWidth, ramp)
+	if data, err := json.Marshal(cachedResult{Lines: lines, Grid: toCached(grid)}); err == nil {
+		if os.MkdirAll(c.Dir, 0755) == nil {
+			os.WriteFile(path, data, 0644)
+		}
+	}
+	return lines, grid
+}
+
+func toCached(grid [][]entity.CharData) [][]cachedCell {
+	out := make([][]cachedCell, len(grid))
+	for y, row := range grid {
+		out[y] = make([]cachedCell, len(row))
+		for x, cell := range row {
+			r, g, b, a := cell.Color.RGBA()
+			out[y][x] = cachedCell{C: cell.OriginalChar, RGBA: [4]uint16{uint16(r), uint16(g), uint16(b), uint16(a)}}
+		}
+	}
+	return out
+}
+
+func fromCached(grid [][]cachedCell) [][]entity.CharData {
+	out := make([][]entity.CharData, len(grid))
+	for y, row := range grid {
+		out[y] = make([]entity.CharData, len(row))
+		for x, cell := range row {
+			out[y][x] = entity.CharData{
+				OriginalChar: cell.C,
+				Char:         cell.C,
+				Color:        color.RGBA64{R: cell.RGBA[0], G: cell.RGBA[1], B: cell.RGBA[2], A: cell.RGBA[3]},
+			}
+		}
+	}
+	return out
+}
@@ -13,8 +13,21 @@ const asciiChars = "@W#80Oocv:,.. "
 
 //"@80QOo:,. "
 
+// DefaultRamp 默认字符集，配置里的 ramp 留空时使用
+const DefaultRamp = asciiChars
+
 // Convert 将图片转换为 ASCII 字符串切片 (高精度区块均值采样版)
 func Convert(img image.Image, targetWidth int) ([]string, [][]entity.CharData) {
+	return ConvertRamp(img, targetWidth, DefaultRamp)
+}
+
+// ConvertRamp 与 Convert 相同，但使用自定义字符集 (从密集到稀疏排列，至少两个字符)
+func ConvertRamp(img image.Image, targetWidth int, ramp string) ([]string, [][]entity.CharData) {
+	chars := []rune(ramp)
+	if len(chars) < 2 {
+		chars = []rune(DefaultRamp)
+	}
+
 	bounds := img.Bounds()
 	width := bounds.Max.X
 	height := bounds.Max.Y
@@ -75,7 +88,7 @@ func Convert(img image.Image, targetWidth int) ([]string, [][]entity.CharData) {
 			}
 
 			// 4. 使用平均颜色进行字符映射
-			char := pixelToASCII(avgColor)
+			char := pixelToASCII(avgColor, chars)
 
 			lineBuilder.WriteString(char)
 
@@ -93,7 +106,7 @@ func Convert(img image.Image, targetWidth int) ([]string, [][]entity.CharData) {
 }
 
 // pixelToASCII 将单个像素颜色转换为 ASCII 字符
-func pixelToASCII(c color.Color) string {
+func pixelToASCII(c color.Color, chars []rune) string {
 	r, g, b, a := c.RGBA() // 提取全部 4 个通道
 
 	if a < 6553 {
@@ -103,11 +116,11 @@ func pixelToASCII(c color.Color) string {
 	// 计算有效像素的灰度值
 	gray := 0.299*float64(r>>8) + 0.587*float64(g>>8) + 0.114*float64(b>>8)
 
-	idx := int(gray / 255 * float64(len(asciiChars)-1))
+	idx := int(gray / 255 * float64(len(chars)-1))
 
-	if idx >= len(asciiChars) {
-		idx = len(asciiChars) - 1
+	if idx >= len(chars) {
+		idx = len(chars) - 1
 	}
 
-	return string(asciiChars[idx])
+	return string(chars[idx])
 }
@@ -0,0 +1,144 @@
+// Package control provides the local HTTP control channel between CLI commands and a running pet
+package control
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"time"
+
+	"0xPet/config"
+)
+
+// 对外暴露的路径
+const (
+	PathPing = "/ping" // GET：探测是否有宠物在运行
+	PathJob  = "/job"  // POST：提交一个 JobEvent
+)
+
+// 任务事件的阶段
+const (
+	PhaseStart    = "start"
+	PhaseProgress = "progress"
+	PhaseExit     = "exit"
+)
+
+// JobEvent `0xpet watch` 发给宠物的任务进度
+type JobEvent struct {
+	ID       string   `json:"id"`                 // 任务标识，同一次 watch 的所有事件相同
+	Command  string   `json:"command"`            // 命令行，比如 "make test"
+	Phase    string   `json:"phase"`              // start / progress / exit
+	Line     string   `json:"line,omitempty"`     // progress：最新一行输出
+	ExitCode int      `json:"exit_code"`          // exit：退出码
+	Duration float64  `json:"duration,omitempty"` // exit：耗时 (秒)
+	Tail     []string `json:"tail,omitempty"`     // exit：stderr 的最后几行
+}
+
+// Server 宠物一侧的控制端点，只绑定在本机地址上
+type Server struct {
+	addr  string
+	onJob func(JobEvent)
+	srv   *http.Server
+}
+
+// NewServer 创建控制端点；onJob 在 HTTP 协程里调用，必须是并发安全的
+func NewServer(addr string, onJob func(JobEvent)) *Server {
+	return &Server{addr: addr, onJob: onJob}
+}
+
+// Start 绑定地址并在后台开始服务
+func (s *Server) Start() error {
+	if err := config.CheckLoopback(s.addr); err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(PathPing, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "0xPet")
+	})
+	mux.HandleFunc(PathJob, s.handleJob)
+	s.srv = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		if err := s.srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			log.Println("控制端点异常退出:", err)
+		}
+	}()
+	return nil
+}
+
+// Close 关闭端点
+func (s *Server) Close() error {
+	if s.srv == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return s.srv.Shutdown(ctx)
+}
+
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	// 只接受 JSON：浏览器里的网页不经过 CORS 预检就只能发 text/plain 等简单请求，这样就伪造不了任务事件
+	if mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mt != "application/json" {
+		http.Error(w, "content type must be application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+	var ev JobEvent
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024)).Decode(&ev); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.onJob(ev)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Client CLI 一侧的客户端
+type Client struct {
+	base   string
+	client *http.Client
+}
+
+// NewClient 创建指向 addr (比如 "127.0.0.1:9466") 的客户端
+func NewClient(addr string) *Client {
+	return &Client{base: "http://" + addr, client: &http.Client{Timeout: 2 * time.Second}}
+}
+
+// Ping 探测是否有宠物在监听
+func (c *Client) Ping() bool {
+	resp, err := c.client.Get(c.base + PathPing)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+// SendJob 提交一个任务事件
+func (c *Client) SendJob(ev JobEvent) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Post(c.base+PathJob, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("宠物返回 %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package control
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// progressEvery 进度事件的最小间隔，防止刷屏的命令把宠物淹没
+const progressEvery = 500 * time.Millisecond
+
+// Job 运行一条命令，把输出原样转发给终端，同时把进度通过 OnEvent 报告出去；
+// 子进程不接 stdin (读到的是空设备)，交互式命令会直接读到 EOF，而不是和宠物抢终端输入
+type Job struct {
+	Args      []string       // 命令及参数
+	Stdout    io.Writer      // 子进程 stdout 的去向
+	Stderr    io.Writer      // 子进程 stderr 的去向
+	OnEvent   func(JobEvent) // 事件回调，在 Run 的调用协程或输出读取协程里调用
+	TailLines int            // 失败时附带的 stderr 行数
+
+	mu       sync.Mutex
+	tail     []string
+	lastSent time.Time
+}
+
+// Run 运行命令直到结束，返回它的退出码；命令无法启动时返回 127
+func (j *Job) Run() int {
+	if len(j.Args) == 0 {
+		return 127
+	}
+	if j.TailLines <= 0 {
+		j.TailLines = 4
+	}
+
+	command := strings.Join(j.Args, " ")
+	id := fmt.Sprintf("%d", time.Now().UnixNano())
+	j.OnEvent(JobEvent{ID: id, Command: command, Phase: PhaseStart})
+	start := time.Now()
+
+	cmd := exec.Command(j.Args[0], j.Args[1:]...)
+	cmd.Stdin = nil // 明确不接 stdin，exec 会接到空设备
+	stdout, err1 := cmd.StdoutPipe()
+	stderr, err2 := cmd.StderrPipe()
+	if err := errors.Join(err1, err2); err != nil {
+		return j.finish(id, command, start, 127, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return j.finish(id, command, start, 127, err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); j.pump(id, command, stdout, j.Stdout, false) }()
+	go func() { defer wg.Done(); j.pump(id, command, stderr, j.Stderr, true) }()
+	wg.Wait()
+
+	err := cmd.Wait()
+	code := 0
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		code = exitErr.ExitCode()
+		err = nil
+	} else if err != nil {
+		code = 1
+	}
+	return j.finish(id, command, start, code, err)
+}
+
+// pump 逐行转发输出，并按节流间隔上报最新一行；
+// 遇到超长的行时不再按行处理，剩下的输出原样转发 (没有去向时丢弃)，保证管道一直被读空，子进程不会卡在写输出上
+func (j *Job) pump(id, command string, r io.Reader, w io.Writer, isStderr bool) {
+	defer func() {
+		if w == nil {
+			w = io.Discard
+		}
+		io.Copy(w, r)
+	}()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if w != nil {
+			fmt.Fprintln(w, line)
+		}
+
+		j.mu.Lock()
+		if isStderr {
+			j.tail = append(j.tail, line)
+			if len(j.tail) > j.TailLines {
+				j.tail = j.tail[len(j.tail)-j.TailLines:]
+			}
+		}
+		send := time.Since(j.lastSent) >= progressEvery && strings.TrimSpace(line) != ""
+		if send {
+			j.lastSent = time.Now()
+		}
+		j.mu.Unlock()
+
+		if send {
+			j.OnEvent(JobEvent{ID: id, Command: command, Phase: PhaseProgress, Line: line})
+		}
+	}
+}
+
+func (j *Job) finish(id, command string, start time.Time, code int, err error) int {
+	j.mu.Lock()
+	tail := append([]string(nil), j.tail...)
+	j.mu.Unlock()
+	if err != nil {
+		tail = append(tail, err.Error())
+	}
+
+	j.OnEvent(JobEvent{
+		ID:       id,
+		Command:  command,
+		Phase:    PhaseExit,
+		ExitCode: code,
+		Duration: time.Since(start).Seconds(),
+		Tail:     tail,
+	})
+	return code
+}
@@ -1,6 +1,11 @@
 package entity
 
-import "image/color"
+import (
+	"image/color"
+	"time"
+
+	"0xPet/internal/monitor"
+)
 
 // 【新增】单个字符的数据单元
 type CharData struct {
@@ -22,4 +27,17 @@ type Pet struct {
 	CPUUsage   float64 // CPU 使用率 (0-100)
 	MemUsage   float64 // 内存 使用率 (0-100)
 	IsStressed bool    // 是否处于高压状态 (CPU > 80)
+
+	// 【新增】完整的系统指标快照 (每核/负载/交换/磁盘/网络)，只由主循环每帧同步，不要在其他协程读写
+	Metrics monitor.Metrics
+
+	// 【新增】高压时的进程排行 (只有 IsStressed 期间才会被采样)，和 Metrics 一起由主循环每帧同步
+	TopProcs monitor.TopProcs
+
+	// 【新增】由告警规则等外部事件驱动的表现
+	State       string      // 当前行为状态，见 State* 常量
+	Tint        color.Color // 整体着色，nil 表示按正常配色绘制
+	Bubble      string      // 当前气泡文字
+	BubbleUntil time.Time   // 气泡消失的时间
+	GlitchUntil time.Time   // 乱码爆发结束的时间
 }
@@ -0,0 +1,109 @@
+package entity
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"strings"
+	"time"
+)
+
+// 宠物的行为状态
+const (
+	StateIdle         = ""             // 平静
+	StateStressed     = "stressed"     // 高压 (会触发进程采样)
+	StateDisconnected = "disconnected" // 指标来源失联，数据已过期
+	StateWorking      = "working"      // 正在等一条命令跑完
+	StateHappy        = "happy"        // 命令成功
+	StateFailed       = "failed"       // 命令失败
+	StateBusy         = "busy"         // 有被关注的长任务在运行
+	StateSleepy       = "sleepy"       // 电池电量低
+	StateHot          = "hot"          // 机器过热
+	StateLanded       = "landed"       // 重力模式下刚摔到地面上
+)
+
+// States 所有可以在配置里使用的状态名
+var States = []string{
+	StateStressed, StateDisconnected, StateWorking, StateHappy, StateFailed, StateBusy, StateSleepy, StateHot, StateLanded,
+}
+
+// CheckState 检查状态名是否有效，空字符串 (不改变状态) 也有效
+func CheckState(s string) error {
+	if s == StateIdle {
+		return nil
+	}
+	for _, st := range States {
+		if s == st {
+			return nil
+		}
+	}
+	return fmt.Errorf("未知的状态 %q，可选值: %s", s, strings.Join(States, ", "))
+}
+
+// Reaction 外部事件 (告警规则、日志、命令结果等) 对宠物提出的反应请求
+type Reaction struct {
+	Source string        // 来源标识，比如 "rule:cpu-high"；同一来源的新反应会覆盖旧的
+	State  string        // 切换到的状态，空字符串表示不改变
+	Color  color.Color   // 着色，nil 表示不改变
+	Bubble string        // 气泡文字，空字符串表示不弹气泡
+	Glitch bool          // 是否来一次乱码爆发
+	Clear  bool          // 撤销该来源之前设置的状态与颜色
+	Hold   time.Duration // 气泡/乱码的持续时间，0 表示用默认值
+	Expire time.Duration // 状态与着色自动撤销的时间，0 表示一直保持到被 Clear
+}
+
+// reactionJSON Reaction 在录制文件里的格式：颜色写成 "#rrggbbaa"
+type reactionJSON struct {
+	Source string        `json:"source"`
+	State  string        `json:"state,omitempty"`
+	Color  string        `json:"color,omitempty"`
+	Bubble string        `json:"bubble,omitempty"`
+	Glitch bool          `json:"glitch,omitempty"`
+	Clear  bool          `json:"clear,omitempty"`
+	Hold   time.Duration `json:"hold,omitempty"`
+	Expire time.Duration `json:"expire,omitempty"`
+}
+
+func (r Reaction) MarshalJSON() ([]byte, error) {
+	j := reactionJSON{Source: r.Source, State: r.State, Bubble: r.Bubble, Glitch: r.Glitch, Clear: r.Clear, Hold: r.Hold, Expire: r.Expire}
+	if r.Color != nil {
+		c := color.RGBAModel.Convert(r.Color).(color.RGBA)
+		j.Color = fmt.Sprintf("#%02x%02x%02x%02x", c.R, c.G, c.B, c.A)
+	}
+	return json.Marshal(j)
+}
+
+func (r *Reaction) UnmarshalJSON(data []byte) error {
+	var j reactionJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	*r = Reaction{Source: j.Source, State: j.State, Bubble: j.Bubble, Glitch: j.Glitch, Clear: j.Clear, Hold: j.Hold, Expire: j.Expire}
+	if j.Color != "" {
+		c, err := ParseColor(j.Color)
+		if err != nil {
+			return err
+		}
+		r.Color = c
+	}
+	return nil
+}
+
+// ParseColor 解析 "#rrggbb" 或 "#rrggbbaa" 形式的颜色
+func ParseColor(s string) (color.RGBA, error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	c := color.RGBA{A: 255}
+	var err error
+	switch len(hex) {
+	case 6:
+		_, err = fmt.Sscanf(hex, "%02x%02x%02x", &c.R, &c.G, &c.B)
+	case 8:
+		_, err = fmt.Sscanf(hex, "%02x%02x%02x%02x", &c.R, &c.G, &c.B, &c.A)
+	default:
+		return c, fmt.Errorf("颜色格式应为 #rrggbb: %q", s)
+	}
+	if err != nil {
+		return c, fmt.Errorf("颜色格式应为 #rrggbb: %q", s)
+	}
+	return c, nil
+}
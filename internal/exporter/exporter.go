@@ -0,0 +1,227 @@
+// Package exporter provides a Prometheus text-format endpoint for monitor samples and pet state
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"0xPet/internal/entity"
+	"0xPet/internal/monitor"
+)
+
+// PetStatus 宠物当前的状态快照
+type PetStatus struct {
+	State    string        // 行为状态，空字符串表示平静
+	Stressed bool          // 是否处于高压状态
+	Alerts   []AlertStatus // 每条告警规则的状态
+}
+
+// AlertStatus 单条告警规则的状态
+type AlertStatus struct {
+	Name   string
+	Firing bool
+}
+
+// Server 本地 HTTP 导出端点，GET /metrics 返回 Prometheus 文本格式
+type Server struct {
+	addr    string
+	metrics func() monitor.Metrics
+	status  func() PetStatus
+	srv     *http.Server
+}
+
+// New 创建导出端点；metrics 和 status 会在每次抓取时被调用，必须是并发安全的
+func New(addr string, metrics func() monitor.Metrics, status func() PetStatus) *Server {
+	return &Server{addr: addr, metrics: metrics, status: status}
+}
+
+// Start 绑定地址并在后台开始服务，端口被占用等错误会立即返回
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	s.srv = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		if err := s.srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			log.Println("指标端点异常退出:", err)
+		}
+	}()
+	log.Println("指标端点已启动: http://" + ln.Addr().String() + "/metrics")
+	return nil
+}
+
+// Close 关闭端点
+func (s *Server) Close() error {
+	if s.srv == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return s.srv.Shutdown(ctx)
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	WriteMetrics(w, s.metrics(), s.status())
+}
+
+// WriteMetrics 把一次采样和宠物状态按 Prometheus 文本格式写出
+// 单块电池的明细和进程列表不导出：前者已经汇总成平均电量，后者以 pid 作标签会让序列数无限增长
+func WriteMetrics(w io.Writer, m monitor.Metrics, st PetStatus) {
+	e := &encoder{w: w}
+
+	e.family("oxpet_sample_timestamp_seconds", "Unix time of the latest monitor sample.")
+	if !m.Time.IsZero() {
+		e.sample("", float64(m.Time.UnixNano())/1e9)
+	}
+
+	e.family("oxpet_cpu_usage_percent", "Total CPU usage in percent.")
+	e.sample("", m.CPU)
+
+	e.family("oxpet_cpu_core_usage_percent", "Per-core CPU usage in percent.")
+	for i, c := range m.PerCore {
+		e.sample(labels("core", strconv.Itoa(i)), c)
+	}
+
+	e.family("oxpet_memory_usage_percent", "Virtual memory usage in percent.")
+	e.sample("", m.Mem)
+
+	e.family("oxpet_swap_usage_percent", "Swap usage in percent.")
+	e.sample("", m.Swap)
+
+	e.family("oxpet_load_average", "System load average.")
+	e.sample(labels("period", "1m"), m.Load1)
+	e.sample(labels("period", "5m"), m.Load5)
+	e.sample(labels("period", "15m"), m.Load15)
+
+	e.family("oxpet_disk_usage_percent", "Disk space usage per mount point in percent.")
+	for _, d := range m.Disks {
+		e.sample(labels("mount", d.Mount), d.UsedPercent)
+	}
+
+	e.family("oxpet_disk_read_bytes_per_second", "Disk read throughput.")
+	e.sample("", m.DiskRead)
+
+	e.family("oxpet_disk_write_bytes_per_second", "Disk write throughput.")
+	e.sample("", m.DiskWrite)
+
+	e.family("oxpet_network_receive_bytes_per_second", "Network receive throughput per interface.")
+	for _, n := range m.Net {
+		e.sample(labels("interface", n.Name), n.RecvRate)
+	}
+
+	e.family("oxpet_network_transmit_bytes_per_second", "Network transmit throughput per interface.")
+	for _, n := range m.Net {
+		e.sample(labels("interface", n.Name), n.SentRate)
+	}
+
+	// 没有电池的机器不输出电池序列，免得被误当成 0% 电量
+	e.family("oxpet_battery_percent", "Average battery charge in percent.")
+	if m.Power.HasBattery {
+		e.sample("", m.Power.Percent)
+	}
+
+	e.family("oxpet_on_battery", "Whether the machine runs on battery (1) or external power (0).")
+	if m.Power.HasBattery {
+		e.sample("", boolValue(m.Power.OnBattery))
+	}
+
+	e.family("oxpet_temperature_celsius", "Temperature per sensor.")
+	for _, t := range m.Temps {
+		e.sample(labels("sensor", t.Sensor), t.Celsius)
+	}
+
+	// cgroup 与 PSI 只在使用 cgroup 来源时才有数据
+	cg := m.Cgroup
+	e.family("oxpet_cgroup_cpu_usage_percent", "CPU usage relative to the cgroup CPU limit in percent.")
+	if cg != nil {
+		e.sample("", cg.CPUPercent)
+	}
+
+	e.family("oxpet_cgroup_memory_usage_percent", "Memory usage relative to the cgroup memory limit in percent.")
+	if cg != nil {
+		e.sample("", cg.MemPercent)
+	}
+
+	e.family("oxpet_pressure_avg10_percent", "Share of time stalled on a resource over the last 10 seconds (PSI).")
+	if cg != nil {
+		for _, p := range []struct {
+			resource string
+			psi      monitor.PSI
+		}{{"cpu", cg.CPUPressure}, {"memory", cg.MemPressure}, {"io", cg.IOPressure}} {
+			e.sample(labels("resource", p.resource, "kind", "some"), p.psi.SomeAvg10)
+			e.sample(labels("resource", p.resource, "kind", "full"), p.psi.FullAvg10)
+		}
+	}
+
+	// 自定义指标按名字排序，保证每次抓取的顺序一致
+	e.family("oxpet_custom_metric", "User-defined metrics from custom_metrics, by name.")
+	names := make([]string, 0, len(m.Custom))
+	for name := range m.Custom {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		e.sample(labels("name", name), m.Custom[name])
+	}
+
+	e.family("oxpet_pet_stressed", "Whether the pet is stressed (1) or calm (0).")
+	e.sample("", boolValue(st.Stressed))
+
+	// 每个已知状态都输出一条，当前状态为 1、其余为 0，切换状态时旧的序列不会消失
+	e.family("oxpet_pet_state", "Current pet behavior state; the active state has value 1, all others 0.")
+	e.sample(labels("state", "idle"), boolValue(st.State == entity.StateIdle))
+	for _, state := range entity.States {
+		e.sample(labels("state", state), boolValue(st.State == state))
+	}
+
+	e.family("oxpet_alert_firing", "Whether each alert rule is firing (1) or not (0).")
+	for _, a := range st.Alerts {
+		e.sample(labels("rule", a.Name), boolValue(a.Firing))
+	}
+}
+
+// encoder 逐行写出文本格式，HELP/TYPE 只写一次
+type encoder struct {
+	w    io.Writer
+	name string
+}
+
+func (e *encoder) family(name, help string) {
+	e.name = name
+	fmt.Fprintf(e.w, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+}
+
+func (e *encoder) sample(lbls string, v float64) {
+	fmt.Fprintf(e.w, "%s%s %s\n", e.name, lbls, strconv.FormatFloat(v, 'g', -1, 64))
+}
+
+// labels 按 k1, v1, k2, v2... 生成 {k1="v1",k2="v2"} 形式的标签，值按规范转义
+func labels(kv ...string) string {
+	esc := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	pairs := make([]string, 0, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		pairs = append(pairs, kv[i]+`="`+esc.Replace(kv[i+1])+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
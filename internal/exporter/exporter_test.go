@@ -0,0 +1,88 @@
+package exporter
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"0xPet/internal/entity"
+	"0xPet/internal/monitor"
+)
+
+// 改了输出格式后用 go test ./internal/exporter -update 重新生成 testdata 里的基准文件
+var update = flag.Bool("update", false, "重新生成 testdata 里的基准文件")
+
+func TestWriteMetricsGolden(t *testing.T) {
+	full := monitor.Metrics{
+		Time:    time.Unix(1700000000, 500000000),
+		CPU:     42.5,
+		PerCore: []float64{40, 45},
+		Mem:     63.2, Swap: 1,
+		Load1: 0.5, Load5: 0.25, Load15: 0.125,
+		Disks:    []monitor.DiskUsage{{Mount: "/", UsedPercent: 71.3}, {Mount: `C:\`, UsedPercent: 10}},
+		DiskRead: 1024, DiskWrite: 2048,
+		Net:    []monitor.NetIO{{Name: "eth0", RecvRate: 1500, SentRate: 300}},
+		Custom: map[string]float64{"queue": 37, "errors": 2},
+		Cgroup: &monitor.CgroupStats{
+			CPUPercent: 55, MemPercent: 80,
+			CPUPressure: monitor.PSI{SomeAvg10: 12.5},
+			MemPressure: monitor.PSI{SomeAvg10: 1, FullAvg10: 0.75},
+			IOPressure:  monitor.PSI{SomeAvg10: 3, FullAvg10: 2},
+		},
+		Power: monitor.Power{HasBattery: true, OnBattery: true, Percent: 18},
+		Temps: []monitor.Temp{{Sensor: "coretemp", Celsius: 71}},
+	}
+
+	tests := []struct {
+		name string
+		m    monitor.Metrics
+		st   PetStatus
+	}{
+		{"full", full, PetStatus{
+			State:    entity.StateSleepy,
+			Stressed: true,
+			Alerts:   []AlertStatus{{Name: "cpu-high", Firing: true}, {Name: `say "hi"`}},
+		}},
+		// 还没有采样、没有电池和 cgroup 时只输出 HELP/TYPE 和必有的序列
+		{"empty", monitor.Metrics{}, PetStatus{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			WriteMetrics(&buf, tt.m, tt.st)
+			checkGolden(t, tt.name, buf.Bytes())
+		})
+	}
+}
+
+// checkGolden 把 got 与 testdata/<name>.txt 比较
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".txt")
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("读取基准文件失败 (用 -update 生成): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("输出与 %s 不同:\n%s", path, got)
+	}
+}
+
+func TestLabelsEscape(t *testing.T) {
+	got := labels("a", `x\y`, "b", "say \"hi\"\n")
+	if want := `{a="x\\y",b="say \"hi\"\n"}`; got != want {
+		t.Errorf("labels = %s, 期望 %s", got, want)
+	}
+}
@@ -0,0 +1,105 @@
+// Package fsutil provides crash-safe file writes shared by config and state saving
+package fsutil
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// BackupSuffix 上一个版本的备份文件后缀
+const BackupSuffix = ".bak"
+
+// WriteAtomic 原子地写入文件：先写到同目录下的临时文件并 fsync，再改名覆盖目标；
+// 目标原来的内容保留为 path.bak。中途崩溃或磁盘写满时，目标要么是旧内容，要么是新内容，不会只写一半，
+// 也不会有哪一刻目标文件不存在 (配置监听器或并发的读取不会看到文件消失)
+func WriteAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	// 1. 写临时文件并落盘
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	cleanup := func(err error) error {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		return cleanup(err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return cleanup(err)
+	}
+	if err := tmp.Close(); err != nil {
+		return cleanup(err)
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		return cleanup(err)
+	}
+
+	// 2. 旧文件硬链接 (不支持时复制) 为 .bak，滚动覆盖上一份备份；目标本身保持不动
+	if _, err := os.Stat(path); err == nil {
+		if err := backup(path); err != nil {
+			return cleanup(err)
+		}
+	}
+
+	// 3. 临时文件直接改名覆盖目标
+	if err := os.Rename(tmpName, path); err != nil {
+		return cleanup(err)
+	}
+
+	// 4. 目录也要落盘，改名才算真正写进去 (Windows 不支持对目录 fsync)
+	if runtime.GOOS != "windows" {
+		if d, err := os.Open(dir); err == nil {
+			d.Sync()
+			d.Close()
+		}
+	}
+	return nil
+}
+
+// backup 把 path 当前的内容保存为 path.bak：先在临时名字上建硬链接或副本，再改名覆盖旧备份
+func backup(path string) error {
+	tmpName := path + BackupSuffix + ".tmp"
+	os.Remove(tmpName)
+	if err := os.Link(path, tmpName); err != nil {
+		if err := copyFile(path, tmpName); err != nil {
+			os.Remove(tmpName)
+			return err
+		}
+	}
+	if err := os.Rename(tmpName, path+BackupSuffix); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
+
+func copyFile(from, to string) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	dst, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
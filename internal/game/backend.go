@@ -0,0 +1,95 @@
+//go:build !headless
+
+package game
+
+import (
+	"io"
+	"io/fs"
+	"log"
+	"time"
+
+	"0xPet/internal/input"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// errTerminate Update 返回它表示正常退出，ebiten 收到后结束 RunGame 而不报错
+var errTerminate = ebiten.Termination
+
+// ebitenInput 从 ebiten 读取真实的鼠标、键盘与拖拽文件
+type ebitenInput struct {
+	last time.Time // 上一次 Poll 的时间
+}
+
+var _ input.Input = (*ebitenInput)(nil)
+
+func (e *ebitenInput) Poll() input.Frame {
+	var f input.Frame
+	now := time.Now()
+	if !e.last.IsZero() {
+		f.DT = now.Sub(e.last)
+	}
+	e.last = now
+	f.X, f.Y = ebiten.CursorPosition()
+
+	buttons := []struct {
+		b  input.Button
+		eb ebiten.MouseButton
+	}{{input.ButtonLeft, ebiten.MouseButtonLeft}, {input.ButtonRight, ebiten.MouseButtonRight}}
+	for _, b := range buttons {
+		if ebiten.IsMouseButtonPressed(b.eb) {
+			f.Down |= b.b
+		}
+		if inpututil.IsMouseButtonJustPressed(b.eb) {
+			f.Just |= b.b
+		}
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyEscape) {
+		f.Keys |= input.KeyEscape
+	}
+	f.Drop = droppedFile()
+	return f
+}
+
+// droppedFile 读出这一帧拖进来的第一个文件，没有时返回 nil
+func droppedFile() *input.File {
+	dr := ebiten.DroppedFiles()
+	if dr == nil {
+		return nil
+	}
+	entries, err := fs.ReadDir(dr, ".")
+	if err != nil || len(entries) == 0 {
+		return nil
+	}
+	name := entries[0].Name()
+	file, err := dr.Open(name)
+	if err != nil {
+		log.Println("读取文件失败:", err)
+		return nil
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		log.Println("读取文件失败:", err)
+		return nil
+	}
+	return &input.File{Name: name, Data: data}
+}
+
+// ebitenWindow 真实的 ebiten 窗口
+type ebitenWindow struct{}
+
+var _ input.Window = ebitenWindow{}
+
+func (ebitenWindow) Position() (int, int)   { return ebiten.WindowPosition() }
+func (ebitenWindow) SetPosition(x, y int)   { ebiten.SetWindowPosition(x, y) }
+func (ebitenWindow) Size() (int, int)       { return ebiten.WindowSize() }
+func (ebitenWindow) SetSize(w, h int)       { ebiten.SetWindowSize(w, h) }
+func (ebitenWindow) ScreenSize() (int, int) { return ebiten.ScreenSizeInFullscreen() }
+func (ebitenWindow) SetTPS(tps int)         { ebiten.SetTPS(tps) }
+
+// NativeInput 与 NativeWindow 是 ebiten 的实现，Manager 的 Input/Window 留空时使用；
+// 录制时用它们作为被录制的来源
+func NativeInput() input.Input   { return &ebitenInput{} }
+func NativeWindow() input.Window { return ebitenWindow{} }
@@ -0,0 +1,34 @@
+//go:build !headless
+
+package game
+
+import (
+	"image/color"
+
+	"0xPet/internal/render"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"golang.org/x/image/font"
+)
+
+// ebitenCanvas 在 ebiten 图像上实现 render.Canvas，窗口里的绘制都经过它
+type ebitenCanvas struct {
+	img *ebiten.Image
+}
+
+var _ render.Canvas = ebitenCanvas{}
+
+func (c ebitenCanvas) Text(s string, face font.Face, x, y int, clr color.Color) {
+	text.Draw(c.img, s, face, x, y, clr)
+}
+
+func (c ebitenCanvas) FillRect(x, y, w, h float32, clr color.Color) {
+	vector.DrawFilledRect(c.img, x, y, w, h, clr, false)
+}
+
+// fonts 当前的一对字体，交给 render 包使用
+func (g *Manager) fonts() render.Fonts {
+	return render.Fonts{Normal: g.FontNormal, Small: g.FontSmall}
+}
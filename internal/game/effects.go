@@ -0,0 +1,125 @@
+//go:build !headless
+
+package game
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"0xPet/internal/entity"
+	"0xPet/internal/render"
+)
+
+const (
+	glitchChars   = "!@#$%&*?<>/\\|=+~^"
+	spinnerFrames = `|/-\`
+)
+
+// drawMood 按状态叠加的小动画：犯困时飘 "z"，过热时两侧滴汗 (关闭 ShowAnimation 时不画)
+func (g *Manager) drawMood(c render.Canvas, offsetY int) {
+	if !g.ShowAnimation {
+		return
+	}
+	switch g.MyPet.State {
+	case entity.StateSleepy:
+		g.drawSleep(c, offsetY)
+	case entity.StateHot:
+		g.drawSweat(c, offsetY)
+	}
+}
+
+// drawSleep 右上角依次浮起三个越来越大的 z
+func (g *Manager) drawSleep(c render.Canvas, offsetY int) {
+	t := float64(time.Now().UnixMilli()%3000) / 3000 // 0..1 的循环进度
+	baseX := g.MyPet.Width - 40
+	zs := []struct {
+		s    string
+		dx   int
+		face bool // true 用大字体
+	}{{"z", 0, false}, {"z", 10, false}, {"Z", 20, true}}
+
+	for i, z := range zs {
+		phase := math.Mod(t+float64(i)/3, 1)
+		y := offsetY + 30 - int(phase*30)
+		alpha := uint8(255 * (1 - phase))
+		face := g.FontSmall
+		if z.face {
+			face = g.FontNormal
+		}
+		c.Text(z.s, face, baseX+z.dx, y, color.RGBA{200, 200, 255, alpha})
+	}
+}
+
+// drawSweat 宠物两侧各有一滴汗往下落
+func (g *Manager) drawSweat(c render.Canvas, offsetY int) {
+	t := float64(time.Now().UnixMilli()%1200) / 1200
+	fall := int(t * 40)
+	drop := color.RGBA{120, 200, 255, uint8(255 * (1 - t))}
+
+	c.Text("'", g.FontNormal, 4, offsetY+20+fall, drop)
+	c.Text("'", g.FontNormal, g.MyPet.Width-12, offsetY+30+(fall+20)%40, drop)
+}
+
+// drawJob 命令或长任务运行期间在宠物底部显示转圈动画和最新一行输出
+func (g *Manager) drawJob(c render.Canvas, offsetY int) {
+	g.jobMu.Lock()
+	var cmd, line string
+	if g.job != nil {
+		cmd, line = g.job.Command, g.job.Line
+	} else if len(g.busy) > 0 {
+		// 没有 watch 任务时，显示进程监听发现的长任务
+		names := make([]string, 0, len(g.busy))
+		for _, name := range g.busy {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		cmd = "busy: " + strings.Join(names, ", ")
+	}
+	g.jobMu.Unlock()
+	if cmd == "" {
+		return
+	}
+
+	frame := spinnerFrames[int(time.Now().UnixMilli()/150)%len(spinnerFrames)]
+	lines := []string{fmt.Sprintf("[%c] %s", frame, cmd), line}
+	render.Job(c, g.fonts(), lines, offsetY, g.MyPet.Width, g.MyPet.Height, g.theme)
+}
+
+// drawGlitch 乱码爆发：在静态底图上随机覆写一批字符，不触发全量重绘
+func (g *Manager) drawGlitch(cv render.Canvas, offsetY int) {
+	if !g.ShowGlitch || g.now.After(g.MyPet.GlitchUntil) || len(g.MyPet.Grid) == 0 {
+		return
+	}
+
+	_, fontW, fontH := render.Cells(g.DisplayMode)
+	face := g.fonts().Face(g.DisplayMode)
+
+	rows := len(g.MyPet.Grid)
+	n := rows * len(g.MyPet.Grid[0]) / 12
+	for i := 0; i < n; i++ {
+		r := rand.Intn(rows)
+		row := g.MyPet.Grid[r]
+		if len(row) == 0 {
+			continue
+		}
+		c := rand.Intn(len(row))
+		if row[c].Char == " " {
+			continue
+		}
+		ch := string(glitchChars[rand.Intn(len(glitchChars))])
+		cv.Text(ch, face, c*fontW, offsetY+r*fontH, g.theme.Glitch.RGBA())
+	}
+}
+
+// drawBubble 在宠物底部画一个气泡，过期后自动消失
+func (g *Manager) drawBubble(c render.Canvas, offsetY int) {
+	if g.MyPet.Bubble == "" || g.now.After(g.MyPet.BubbleUntil) {
+		return
+	}
+	render.Bubble(c, g.fonts(), g.MyPet.Bubble, offsetY, g.MyPet.Width, g.MyPet.Height, g.theme)
+}
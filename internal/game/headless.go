@@ -0,0 +1,22 @@
+//go:build headless
+
+package game
+
+import (
+	"errors"
+
+	"0xPet/internal/input"
+)
+
+// 带 headless 标签构建时不链接 ebiten (也就不需要 cgo 与图形库)，只保留 Update 用到的逻辑，
+// 用来在没有显示器的环境里跑拖拽、投掷与菜单的测试：go test -tags headless ./internal/game
+
+// errTerminate Update 返回它表示正常退出
+var errTerminate = errors.New("terminated")
+
+// canvases 没有窗口，也就没有离屏画布
+type canvases struct{}
+
+// NativeInput 与 NativeWindow 在 headless 构建里没有真实窗口，返回空的脚本与内存窗口
+func NativeInput() input.Input   { return &input.Script{} }
+func NativeWindow() input.Window { return &input.FakeWindow{} }
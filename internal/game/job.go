@@ -0,0 +1,141 @@
+package game
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+	"time"
+
+	"0xPet/internal/control"
+	"0xPet/internal/entity"
+	"0xPet/internal/monitor"
+)
+
+const (
+	jobSuccessHold = 5 * time.Second
+	jobFailureHold = 15 * time.Second
+)
+
+// HandleJob 处理 `0xpet watch` 的任务事件，可在任意协程调用
+func (g *Manager) HandleJob(ev control.JobEvent) {
+	source := "job:" + ev.ID
+
+	switch ev.Phase {
+	case control.PhaseStart:
+		g.jobMu.Lock()
+		g.job = &ev
+		g.jobMu.Unlock()
+		g.React(entity.Reaction{Source: source, State: entity.StateWorking})
+
+	case control.PhaseProgress:
+		g.jobMu.Lock()
+		if g.job != nil && g.job.ID == ev.ID {
+			g.job.Line = ev.Line
+		}
+		g.jobMu.Unlock()
+
+	case control.PhaseExit:
+		g.jobMu.Lock()
+		if g.job != nil && g.job.ID == ev.ID {
+			g.job = nil
+		}
+		g.jobMu.Unlock()
+
+		took := time.Duration(ev.Duration * float64(time.Second)).Round(100 * time.Millisecond)
+		if ev.ExitCode == 0 {
+			g.React(entity.Reaction{
+				Source: source,
+				State:  entity.StateHappy,
+				Color:  color.RGBA{80, 255, 120, 255},
+				Bubble: fmt.Sprintf("OK: %s (%s)", ev.Command, took),
+				Expire: jobSuccessHold,
+				Hold:   jobSuccessHold,
+			})
+		} else {
+			bubble := fmt.Sprintf("FAILED (%d): %s", ev.ExitCode, ev.Command)
+			if len(ev.Tail) > 0 {
+				bubble += "\n" + strings.Join(ev.Tail, "\n")
+			}
+			g.React(entity.Reaction{
+				Source: source,
+				State:  entity.StateFailed,
+				Color:  color.RGBA{255, 60, 60, 255},
+				Bubble: bubble,
+				Glitch: true,
+				Expire: jobFailureHold,
+				Hold:   jobFailureHold,
+			})
+		}
+	}
+}
+
+// HandleProcEvent 处理进程监听事件，可在任意协程调用
+// 只有运行超过 busy_after 的长任务才会让宠物忙碌，并在结束时提醒
+func (g *Manager) HandleProcEvent(ev monitor.ProcEvent) {
+	source := fmt.Sprintf("proc:%d", ev.PID)
+
+	switch ev.Kind {
+	case monitor.ProcLong:
+		g.jobMu.Lock()
+		if g.busy == nil {
+			g.busy = map[int32]string{}
+		}
+		g.busy[ev.PID] = ev.Label
+		g.jobMu.Unlock()
+		g.React(entity.Reaction{Source: source, State: entity.StateBusy})
+
+	case monitor.ProcExited:
+		g.jobMu.Lock()
+		delete(g.busy, ev.PID)
+		g.jobMu.Unlock()
+		if !ev.Long {
+			return
+		}
+
+		took := ev.Duration.Round(time.Second)
+		r := entity.Reaction{Source: source, Clear: true, Bubble: fmt.Sprintf("%s finished (%s)", ev.Label, took)}
+		if ev.ExitKnown {
+			r.Bubble = fmt.Sprintf("%s finished (%s, exit %d)", ev.Label, took, ev.ExitCode)
+			if ev.ExitCode != 0 {
+				r = entity.Reaction{
+					Source: source,
+					State:  entity.StateFailed,
+					Color:  color.RGBA{255, 60, 60, 255},
+					Bubble: r.Bubble,
+					Expire: jobFailureHold,
+					Hold:   jobFailureHold,
+				}
+			}
+		}
+		g.React(r)
+	}
+}
+
+// ExitAfter 在 d 之后保存并退出，exitCode 供调用方作为进程退出码 (watch 单次模式)
+func (g *Manager) ExitAfter(d time.Duration, exitCode int) {
+	g.jobMu.Lock()
+	defer g.jobMu.Unlock()
+	g.exitCode = exitCode
+	g.exitAt = time.Now().Add(d)
+}
+
+// Quit 请求在下一帧保存配置并退出，可以在任意协程里调用 (比如收到 SIGTERM 时)
+func (g *Manager) Quit() {
+	g.jobMu.Lock()
+	defer g.jobMu.Unlock()
+	g.exitAt = time.Now()
+}
+
+// exitDue 是否到了 ExitAfter 约定的退出时间
+func (g *Manager) exitDue() bool {
+	g.jobMu.Lock()
+	defer g.jobMu.Unlock()
+	return !g.exitAt.IsZero() && time.Now().After(g.exitAt)
+}
+
+// ExitCode 返回 ExitAfter 设置的退出码
+func (g *Manager) ExitCode() int {
+	g.jobMu.Lock()
+	defer g.jobMu.Unlock()
+	return g.exitCode
+}
@@ -2,26 +2,53 @@
 package game
 
 import (
+	"io"
 	"log"
-	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
 	"time"
 
 	"0xPet/config"
+	"0xPet/internal/ascii"
+	"0xPet/internal/control"
 	"0xPet/internal/entity"
+	"0xPet/internal/exporter"
+	"0xPet/internal/input"
+	"0xPet/internal/logwatch"
 	"0xPet/internal/monitor"
+	"0xPet/internal/paths"
+	"0xPet/internal/physics"
+	"0xPet/internal/remote"
+	"0xPet/internal/render"
+	"0xPet/internal/rules"
+	"0xPet/internal/theme"
 
-	"github.com/hajimehoshi/ebiten/v2"
 	"golang.org/x/image/font"
-	"golang.org/x/image/font/opentype"
 )
 
 type Manager struct {
 	MyPet *entity.Pet
 
-	ShowColor   bool
-	ShowMonitor bool
-	ShowMenu    bool
-	menuAnim    float64
+	// 【新增】启动参数，在 Init 之前设置：配置文件路径 (为空时用 XDG 配置目录) 与环境变量/命令行覆盖项
+	ConfigPath string
+	Overrides  []config.Override
+
+	// 【新增】输入与窗口，在 Init 之前设置；留空时使用 ebiten 的实现，测试与回放时换成 input 包里的假实现
+	Input  input.Input
+	Window input.Window
+
+	// 【新增】Recorder 非 nil 时把主循环每一帧实际用到的输入 (含时钟、采样与反应) 录下来；
+	// Replaying 表示 Input 在回放录制文件，这时不启动监控与各种监听，宠物只看录下来的数据
+	Recorder  *input.Recorder
+	Replaying bool
+
+	ShowColor     bool
+	ShowMonitor   bool
+	ShowGlitch    bool // 【新增】是否播放乱码爆发
+	ShowAnimation bool // 【新增】是否播放状态小动画
+	ShowMenu      bool
+	menuAnim      float64
 
 	// 【新增】显示模式：0=正常, 1=高分辨率, 2=迷你模式
 	DisplayMode int
@@ -34,53 +61,197 @@ type Manager struct {
 	isDragging bool
 	dragStartX int
 	dragStartY int
-	velX       float64
-	velY       float64
+	tracker    physics.Tracker // 【修改】最近的拖拽轨迹，松手时据此估计甩出去的速度
+	body       physics.Body    // 【新增】松手后的滑行交给 physics 按固定步长推进
+	landedAt   time.Time       // 【新增】最近一次落地的时间与力度，用于压扁动画
+	squash     float64
 	lastWinX   int
 	lastWinY   int
 
 	lastTPS        int
 	currentImgPath string
+	isHover        bool
+
+	// 【新增】进程采样器：只在宠物处于高压状态时运行
+	procSampler *monitor.ProcSampler
+
+	// 【新增】当前生效的配置，保存时沿用其中不在运行时修改的字段；
+	// 热重载时整体替换 (不原地修改)，监控协程通过 settings()/engine() 读取
+	cfgMu      sync.RWMutex
+	cfg        *config.Config
+	layers     *config.Layered   // 【新增】文件层与覆盖层，保存时不把临时覆盖写回文件
+	cfgErr     error             // 配置文件当前的错误，有错时不回写，以免覆盖用户正在改的内容
+	cfgUpdates chan configUpdate // 监听协程 -> Update 的配置变更
+	cfgWatcher *config.Watcher
+	configPath string      // 【新增】配置文件位置 (XDG 配置目录)
+	convCache  ascii.Cache // 【新增】字符画转换缓存 (XDG 缓存目录)
+	srcKey     string      // 【新增】当前图片源文件的缓存键
+
+	// 【新增】配色主题：themeName 是配置里的名字 (空表示默认)，theme 是加载好的颜色
+	themeName string
+	theme     *theme.Theme
 
-	menuCanvas *ebiten.Image
-	menuDirty  bool
+	// 【新增】告警规则与反应队列
+	ruleEngine      *rules.Engine
+	history         *monitor.History
+	reactions       chan entity.Reaction
+	activeReactions []activeReaction
+	disconnected    bool
+	sleepy          bool
+	hot             bool
 
-	petCanvas *ebiten.Image
+	// 【新增】监控协程发布的新采样，受 sampleMu 保护；主循环在 Update 开头取走，随这一帧的输入一起录制，
+	// 之后渲染与物理只读 MyPet 上的副本，不和监控协程共享任何数据
+	sampleMu sync.Mutex
+	sample   *input.Sample // 还没被取走的新采样，nil 表示没有
+	power    monitor.Power // 本机的供电状态 (来自帧里的采样)，决定是否切到省电档
+
+	// 【新增】这一帧的时钟 (来自输入帧)，主循环里的计时都用它而不是 time.Now，回放时才能逐帧一致
+	now time.Time
+
+	// 【新增】供指标端点并发读取的状态快照
+	statusMu  sync.Mutex
+	petStatus exporter.PetStatus
+	exporter  *exporter.Server
+
+	// 【新增】当前的指标来源与自定义指标，远程来源在 Close 时要断开连接，自定义指标随配置重启
+	source  monitor.Source
+	plugins *monitor.Plugins
+
+	// 【新增】控制端点与当前正在观察的命令
+	control  *control.Server
+	jobMu    sync.Mutex
+	job      *control.JobEvent // 正在运行的命令，nil 表示没有
+	exitAt   time.Time         // 非零时，到点后自动退出 (watch 单次模式)，受 jobMu 保护
+	exitCode int
+	busy     map[int32]string // 正在运行的长任务：PID -> 显示名，受 jobMu 保护
+
+	canvases  // 菜单与宠物的离屏画布，只在带窗口的构建里存在
+	menuDirty bool
 	isDirty   bool
 }
 
-func (g *Manager) Init() {
+// Init 加载配置、字体与图片，并启动监控、控制端点等后台服务；
+// 【修改】出错时返回错误，由调用方决定怎么退出，而不是在这里直接结束进程
+func (g *Manager) Init() error {
 	g.MyPet = &entity.Pet{}
+	if g.Input == nil {
+		g.Input = NativeInput()
+	}
+	if g.Window == nil {
+		g.Window = NativeWindow()
+	}
+	g.procSampler = monitor.NewProcSampler(5, 2*time.Second)
+	g.reactions = make(chan entity.Reaction, 64)
+	g.cfgUpdates = make(chan configUpdate, 4)
+	g.history = monitor.GetHistory()
+
+	// 【新增】配置、数据与缓存放在 XDG 目录下，第一次运行时把旧版留在程序目录的文件搬过去；
+	// 用 -config 指定了配置文件时不做迁移
+	var moved map[string]string
+	g.configPath = g.ConfigPath
+	if g.configPath == "" {
+		moved = paths.MigrateLegacy()
+		g.configPath = paths.ConfigFile()
+	}
+	g.convCache = ascii.Cache{Dir: filepath.Join(paths.CacheDir(), "ascii")}
 
-	cfg, err := config.Load("config.json")
+	file, err := config.Load(g.configPath)
 	if err != nil {
+		// 【修改】配置写坏时先用默认值跑起来，但不覆盖用户的文件，改好后会自动重新加载
 		log.Println("读取配置失败，使用默认值:", err)
+		file = config.NewDefault()
+		g.cfgErr = err
+		g.React(configErrorReaction(err))
+	} else if newPath, ok := moved[filepath.ToSlash(file.ImagePath)]; ok {
+		file.ImagePath = newPath
+		if err := config.Save(file, g.configPath); err != nil {
+			log.Println("更新图片路径失败:", err)
+		}
+	}
+
+	// 【新增】叠加环境变量与命令行参数
+	g.layers, err = config.Layer(file, g.Overrides)
+	if err != nil {
+		log.Println("环境变量或命令行参数有误，忽略这些覆盖:", err)
+		g.React(configErrorReaction(err))
+		g.Overrides = nil
+		g.layers, _ = config.Layer(file, nil)
 	}
 
+	cfg := g.layers.Effective
+	g.cfg = cfg
 	g.ShowColor = cfg.ShowColor
 	g.ShowMonitor = cfg.ShowMonitor
+	g.ShowGlitch = cfg.ShowGlitch
+	g.ShowAnimation = cfg.ShowAnimation
+	g.DisplayMode = cfg.DisplayMode
+	g.applyTheme(cfg.Theme)
 
-	// 【新增】加载 TTF 字体并生成一大一小两个字库实例
-	fontBytes, err := os.ReadFile("assets/PixelOperatorMono.ttf")
+	// 【新增】编译告警规则，配置写错时退回默认规则
+	compiled, err := rules.Compile(cfg.Rules)
 	if err != nil {
-		log.Fatal("无法加载字体文件:", err)
+		log.Println("告警规则有误，使用默认规则:", err)
+		compiled, _ = rules.Compile(config.DefaultRules())
 	}
-	tt, err := opentype.Parse(fontBytes)
+	g.ruleEngine = rules.NewEngine(compiled)
+
+	// 【新增】加载 TTF 字体并生成一大一小两个字库实例
+	fonts, err := render.LoadFonts(paths.Asset("PixelOperatorMono.ttf"))
 	if err != nil {
-		log.Fatal("解析字体失败:", err)
+		return err
 	}
-	g.FontNormal, _ = opentype.NewFace(tt, &opentype.FaceOptions{Size: 16, DPI: 72})
-	g.FontSmall, _ = opentype.NewFace(tt, &opentype.FaceOptions{Size: 8, DPI: 72})
+	g.FontNormal, g.FontSmall = fonts.Normal, fonts.Small
 
-	imageToLoad := "assets/idle.png"
-	if cfg.ImagePath != "" {
-		if _, err := os.Stat(cfg.ImagePath); err == nil {
-			imageToLoad = cfg.ImagePath
-		}
+	imageToLoad := paths.Asset("idle.png")
+	if p := paths.ResolveImage(cfg.ImagePath); p != "" {
+		imageToLoad = p
 	}
 
 	g.LoadPetImage(imageToLoad)
 
+	// 【新增】可选的 Prometheus 指标端点，端口冲突只记日志，不影响宠物本身
+	if cfg.MetricsEnabled {
+		g.exporter = exporter.New(cfg.MetricsAddr, monitor.GetMetrics, g.PetStatus)
+		if err := g.exporter.Start(); err != nil {
+			log.Println("指标端点启动失败:", err)
+			g.exporter = nil
+		}
+	}
+
+	// 【新增】回放时宠物只看录制文件里的采样与反应，不启动任何实时来源
+	if g.Replaying {
+		return nil
+	}
+
+	// 【新增】选择指标来源 (本机或远程 agent) 并启动监控
+	g.source = newSource(cfg)
+	monitor.StartSource(g.source)
+	g.plugins = monitor.StartPlugins(pluginSpecs(cfg.CustomMetrics))
+	monitor.UsePlugins(g.plugins)
+
+	// 【新增】日志监听：命中规则时通过反应队列通知宠物
+	logwatch.StartAll(cfg.LogWatches, func(m logwatch.Match) {
+		log.Printf("日志命中 %s: %s", m.Path, m.Line)
+		g.React(m.Reaction())
+	})
+
+	// 【新增】进程监听：长任务运行时进入忙碌状态，结束时提醒
+	if patterns := procPatterns(cfg.ProcessWatches); len(patterns) > 0 {
+		monitor.NewProcWatcher(patterns, monitor.Interval, g.HandleProcEvent).Start()
+	}
+
+	// 【新增】本机控制端点：让 `0xpet watch` 把命令进度转发过来
+	g.control = control.NewServer(cfg.ControlAddr, g.HandleJob)
+	if err := g.control.Start(); err != nil {
+		log.Println("控制端点启动失败 (可能已有宠物在运行):", err)
+		g.control = nil
+	}
+
+	// 【新增】配置热重载：文件被修改后在下一帧生效
+	g.cfgWatcher = config.NewWatcher(g.configPath, config.DefaultWatchInterval, g.queueConfig)
+	g.cfgWatcher.Start()
+
 	// 【新增：异步硬件监控协程】
 	// 与主渲染线程完全物理隔离，每 2 秒更新一次数据即可，彻底释放系统 CPU
 	go func() {
@@ -92,17 +263,171 @@ func (g *Manager) Init() {
 			}
 
 			// 低频调用系统 API
-			cpu, mem := monitor.GetStats()
+			m := monitor.GetMetrics()
+			s := input.Sample{Metrics: m, Power: g.localPower(m)}
+
+			// 进程采样器由主循环按状态启停，这里只搬运结果
+			if g.procSampler.Running() {
+				s.TopProcs = g.procSampler.Top()
+			}
+			g.publishSample(s)
+			g.checkConnection()
+			g.checkPower(s)
 
-			// 写入内存，供渲染层 (drawPet) 直接以 O(1) 复杂度读取
-			g.MyPet.CPUUsage = cpu
-			g.MyPet.MemUsage = mem
-			g.MyPet.IsStressed = cpu > 80.0
+			// 用历史数据评估告警规则，状态变化通过反应队列交给主循环
+			g.evaluateRules(time.Now())
+			g.updateAlertStatus()
 
 			// 强制休眠 2 秒 (人类查看 HUD 数据的合理刷新率)
 			time.Sleep(2 * time.Second)
 		}
 	}()
+	return nil
+}
+
+// Close 停止 Init 启动的配置监听、控制端点、指标端点、自定义指标与远程指标来源，在窗口关闭之后调用
+func (g *Manager) Close() {
+	if g.cfgWatcher != nil {
+		g.cfgWatcher.Stop()
+	}
+	if g.control != nil {
+		if err := g.control.Close(); err != nil {
+			log.Println("关闭控制端点失败:", err)
+		}
+	}
+	if g.exporter != nil {
+		if err := g.exporter.Close(); err != nil {
+			log.Println("关闭指标端点失败:", err)
+		}
+	}
+	g.plugins.Stop()
+	if c, ok := g.source.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			log.Println("关闭指标来源失败:", err)
+		}
+	}
+}
+
+// newSource 根据配置选择指标来源：配置了远程地址就看远程机器，否则看本机 (可选按 cgroup 限额折算)
+func newSource(cfg *config.Config) monitor.Source {
+	if cfg.RemoteURL != "" {
+		src, err := remote.NewSource(cfg.RemoteURL, cfg.RemoteMode)
+		if err == nil {
+			return src
+		}
+		log.Println("远程监控配置有误，改为监控本机:", err)
+	}
+
+	local := monitor.LocalSource()
+	if cfg.Cgroup {
+		src, err := monitor.NewCgroupSource(cfg.CgroupRoot, "", local)
+		if err == nil {
+			return src
+		}
+		log.Println("无法读取 cgroup，改为监控整机:", err)
+	}
+	return local
+}
+
+// pluginSpecs 把配置里的自定义指标转换成监控模块的定义，时长写错时使用默认值
+func pluginSpecs(metrics []config.CustomMetric) []monitor.PluginSpec {
+	specs := make([]monitor.PluginSpec, 0, len(metrics))
+	for _, cm := range metrics {
+		spec := monitor.PluginSpec{
+			Name:    cm.Name,
+			Kind:    cm.Type,
+			Command: cm.Command,
+			Path:    cm.Path,
+			Field:   cm.Field,
+		}
+		if d, err := time.ParseDuration(cm.Interval); err == nil {
+			spec.Interval = d
+		} else if cm.Interval != "" {
+			log.Printf("自定义指标 %s 的 interval 无效，使用默认值: %q", cm.Name, cm.Interval)
+		}
+		if d, err := time.ParseDuration(cm.Timeout); err == nil {
+			spec.Timeout = d
+		} else if cm.Timeout != "" {
+			log.Printf("自定义指标 %s 的 timeout 无效，使用默认值: %q", cm.Name, cm.Timeout)
+		}
+		specs = append(specs, spec)
+	}
+	return specs
+}
+
+// procPatterns 把配置转换成进程监听的模式，正则写错的条目会被跳过
+func procPatterns(watches []config.ProcessWatch) []*monitor.ProcPattern {
+	var patterns []*monitor.ProcPattern
+	for _, pw := range watches {
+		re, err := regexp.Compile(pw.Pattern)
+		if err != nil {
+			log.Printf("进程监听的正则有误，跳过 %q: %v", pw.Pattern, err)
+			continue
+		}
+		p := &monitor.ProcPattern{Label: pw.Name, Pattern: re, BusyAfter: 5 * time.Second}
+		if p.Label == "" {
+			p.Label = pw.Pattern
+		}
+		if d, err := time.ParseDuration(pw.BusyAfter); err == nil {
+			p.BusyAfter = d
+		} else if pw.BusyAfter != "" {
+			log.Printf("进程监听 %s 的 busy_after 无效，使用默认值: %q", p.Label, pw.BusyAfter)
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns
+}
+
+// publishSample 由监控协程调用，替换还没被主循环取走的采样
+func (g *Manager) publishSample(s input.Sample) {
+	g.sampleMu.Lock()
+	g.sample = &s
+	g.sampleMu.Unlock()
+}
+
+// takeSample 取走监控协程发布的新采样，没有新采样时返回 nil
+func (g *Manager) takeSample() *input.Sample {
+	g.sampleMu.Lock()
+	defer g.sampleMu.Unlock()
+	s := g.sample
+	g.sample = nil
+	return s
+}
+
+// stampFrame 给这一帧附上时钟、新采样与排队的反应；回放时它们已经录在帧里，队列里重新产生的那份丢掉
+func (g *Manager) stampFrame(f *input.Frame) {
+	queued := g.takeReactions()
+	if g.Replaying {
+		return
+	}
+	if f.Time.IsZero() {
+		f.Time = time.Now()
+	}
+	if f.Sample == nil {
+		f.Sample = g.takeSample()
+	}
+	f.Reactions = append(f.Reactions, queued...)
+}
+
+// applySample 把新采样搬到 MyPet 上，同一帧内的渲染与物理看到的是同一份数据
+func (g *Manager) applySample(s input.Sample) {
+	g.MyPet.CPUUsage = s.Metrics.CPU
+	g.MyPet.MemUsage = s.Metrics.Mem
+	g.MyPet.Metrics = s.Metrics
+	g.MyPet.TopProcs = s.TopProcs
+	g.power = s.Power
+}
+
+// localPower 宠物所在机器的供电状态：看本机时就是采样里的，看远程 agent 时另外读一次本机的
+func (g *Manager) localPower(m monitor.Metrics) monitor.Power {
+	if _, isRemote := g.source.(*remote.Source); !isRemote {
+		return m.Power
+	}
+	p, err := monitor.ReadPower(monitor.PowerSupplyRoot)
+	if err != nil {
+		return monitor.Power{}
+	}
+	return p
 }
 
 func (g *Manager) Layout(outsideWidth, outsideHeight int) (int, int) {
@@ -110,16 +435,35 @@ func (g *Manager) Layout(outsideWidth, outsideHeight int) (int, int) {
 }
 
 func (g *Manager) Update() error {
-	if err := g.handleSystemInput(); err != nil {
+	// 【修改】每帧只读取一次输入，之后的处理都基于这一份快照，录制与回放才能逐帧一致
+	in := g.Input.Poll()
+	g.stampFrame(&in)
+	if g.Recorder != nil {
+		g.Recorder.Record(in)
+	}
+	g.now = in.Time
+	if g.now.IsZero() {
+		g.now = time.Now() // 回放已经放完
+	}
+	if in.Sample != nil {
+		g.applySample(*in.Sample)
+	}
+
+	if err := g.handleSystemInput(in); err != nil {
 		return err
 	}
-	g.handleUIInput()
+	if g.exitDue() {
+		g.saveState()
+		return errTerminate
+	}
+	g.applyReactions(in.Reactions)
+	g.applyConfigUpdates()
+	g.handleUIInput(in)
 	g.updateMenuAnim()
 	if g.ShowMenu && g.menuAnim > 0.9 {
-		g.handleMenuClick()
-		return nil
+		return g.handleMenuClick(in)
 	}
-	g.updatePhysics()
+	g.updatePhysics(in)
 	return nil
 }
 
@@ -141,10 +485,3 @@ func (g *Manager) updateMenuAnim() {
 		}
 	}
 }
-
-func (g *Manager) Draw(screen *ebiten.Image) {
-	if g.menuAnim > 0 {
-		g.drawMenu(screen)
-	}
-	g.drawPet(screen)
-}
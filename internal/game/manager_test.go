@@ -0,0 +1,539 @@
+package game
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"0xPet/config"
+	"0xPet/internal/entity"
+	"0xPet/internal/input"
+	"0xPet/internal/monitor"
+	"0xPet/internal/remote"
+	"0xPet/internal/rules"
+	"0xPet/internal/theme"
+)
+
+// 测试用的屏幕与窗口：宠物 100x150，加上右侧菜单后窗口是 260x180
+const (
+	screenW, screenH = 1280, 720
+	winW, winH       = 100 + MenuWidth, MinMenuH
+)
+
+// newTestManager 不经过 Init 组装一个 Manager：配置写到临时目录，不启动任何后台来源
+func newTestManager(t *testing.T, gravity bool) (*Manager, *input.FakeWindow) {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	file := config.NewDefault()
+	file.Gravity = gravity
+	layers, err := config.Layer(file, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	win := &input.FakeWindow{X: 200, Y: 200, W: winW, H: winH, ScreenW: screenW, ScreenH: screenH}
+	g := &Manager{
+		MyPet:       &entity.Pet{Width: 100, Height: 150},
+		Input:       &input.Script{Window: win},
+		Window:      win,
+		cfg:         layers.Effective,
+		layers:      layers,
+		configPath:  filepath.Join(t.TempDir(), "config.json"),
+		theme:       theme.Default(),
+		procSampler: monitor.NewProcSampler(5, time.Second),
+		reactions:   make(chan entity.Reaction, 64),
+	}
+	g.ShowColor, g.ShowMonitor = g.cfg.ShowColor, g.cfg.ShowMonitor
+	return g, win
+}
+
+// play 依次播放 frames，之后再空跑 idle 帧；返回 Update 的第一个错误
+func play(g *Manager, frames []input.Frame, idle int) error {
+	s := g.Input.(*input.Script)
+	s.Frames = append(s.Frames, frames...)
+	for !s.Done() || idle > 0 {
+		if s.Done() {
+			idle--
+		}
+		if err := g.Update(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// concat 把几段脚本接起来
+func concat(parts ...[]input.Frame) []input.Frame {
+	var out []input.Frame
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
+
+// openMenu 右键打开菜单，并等展开动画播完
+func openMenu(x, y int) []input.Frame {
+	return concat(input.Click(x, y, input.ButtonRight), input.Hold(x, y, 0, 10))
+}
+
+// menuRow 菜单第 i 行中间的屏幕坐标 (窗口在 win 的位置时)
+func menuRow(win *input.FakeWindow, i int) (x, y int) {
+	return win.X + win.W - MenuWidth/2, win.Y + StartY + i*RowHeight + RowHeight/2
+}
+
+func TestPhysics(t *testing.T) {
+	tests := []struct {
+		name    string
+		gravity bool
+		frames  []input.Frame
+		idle    int
+		check   func(t *testing.T, g *Manager, win *input.FakeWindow)
+	}{
+		{
+			name: "drag and hold still",
+			// 拖过去之后停住超过 ThrowWindow 再松手，不会甩出去
+			frames: concat(input.Drag(250, 250, 450, 350, 10)[:11], input.Hold(450, 350, input.ButtonLeft, 12), input.Click(450, 350, 0)),
+			idle:   10,
+			check: func(t *testing.T, g *Manager, win *input.FakeWindow) {
+				if win.X != 400 || win.Y != 300 {
+					t.Errorf("window at (%d, %d), want (400, 300)", win.X, win.Y)
+				}
+				if g.body.Moving() {
+					t.Error("body still moving after a still release")
+				}
+			},
+		},
+		{
+			name:   "throw slides and stops",
+			frames: input.Drag(250, 250, 330, 250, 4),
+			idle:   600,
+			check: func(t *testing.T, g *Manager, win *input.FakeWindow) {
+				if win.X <= 280 {
+					t.Errorf("window x = %d, want it to slide past the release point 280", win.X)
+				}
+				if win.Y != 200 {
+					t.Errorf("window y = %d, want 200 (horizontal throw)", win.Y)
+				}
+				if g.body.Moving() {
+					t.Error("body still moving after 10s")
+				}
+			},
+		},
+		{
+			name:   "bounce off the right edge",
+			frames: input.Drag(250, 250, 550, 250, 3),
+			idle:   600,
+			check: func(t *testing.T, g *Manager, win *input.FakeWindow) {
+				if right := screenW - winW; win.X >= right {
+					t.Errorf("window x = %d, want it to bounce back from %d", win.X, right)
+				}
+			},
+		},
+		{
+			name:    "gravity lands on the floor",
+			gravity: true,
+			frames:  input.Drag(250, 250, 270, 240, 4),
+			idle:    600,
+			check: func(t *testing.T, g *Manager, win *input.FakeWindow) {
+				if floor := screenH - winH; win.Y != floor {
+					t.Errorf("window y = %d, want it resting on the floor at %d", win.Y, floor)
+				}
+				if g.body.Moving() {
+					t.Error("body still moving after 10s")
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, win := newTestManager(t, tt.gravity)
+			// 每一帧都检查窗口没有跑出屏幕
+			s := g.Input.(*input.Script)
+			s.Frames = tt.frames
+			for n := 0; !s.Done() || n < tt.idle; {
+				if s.Done() {
+					n++
+				}
+				if err := g.Update(); err != nil {
+					t.Fatal(err)
+				}
+				if win.X < 0 || win.Y < 0 || win.X+win.W > screenW || win.Y+win.H > screenH {
+					t.Fatalf("window left the screen: (%d, %d)", win.X, win.Y)
+				}
+			}
+			tt.check(t, g, win)
+		})
+	}
+}
+
+// TestLanding 重力模式下松手落地：落地那一帧压扁，随后切到 landed 状态，过一会儿回弹并恢复平静
+func TestLanding(t *testing.T) {
+	g, win := newTestManager(t, true)
+	if err := play(g, input.Click(250, 250, input.ButtonLeft), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// 1. 逐帧推进，直到第一次撞到地面
+	for n := 0; g.squash == 0; n++ {
+		if n > 600 {
+			t.Fatalf("no landing after 600 frames, window at (%d, %d)", win.X, win.Y)
+		}
+		if err := play(g, nil, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 撞地之后同一帧里剩下的步长已经弹起了一点
+	if floor := screenH - winH; win.Y > floor || win.Y < floor-10 {
+		t.Errorf("squashed at y = %d, want on the floor at %d", win.Y, floor)
+	}
+	if g.squash <= 0 || g.squash > maxSquash {
+		t.Errorf("squash = %v, want in (0, %v]", g.squash, maxSquash)
+	}
+	if sx, sy := g.squashScale(); sx <= 1 || sy >= 1 {
+		t.Errorf("scale at impact = (%v, %v), want wider and flatter", sx, sy)
+	}
+
+	// 2. 反应在下一帧生效
+	if err := play(g, nil, 1); err != nil {
+		t.Fatal(err)
+	}
+	if g.MyPet.State != entity.StateLanded {
+		t.Errorf("state = %q after landing, want %q", g.MyPet.State, entity.StateLanded)
+	}
+
+	// 3. 压扁动画与 landed 状态都会结束
+	for end := g.now.Add(landedExpire + time.Second); g.now.Before(end); {
+		if err := play(g, nil, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sx, sy := g.squashScale(); sx != 1 || sy != 1 {
+		t.Errorf("scale = (%v, %v) long after landing, want (1, 1)", sx, sy)
+	}
+	if g.MyPet.State != entity.StateIdle {
+		t.Errorf("state = %q after %v, want idle", g.MyPet.State, landedExpire)
+	}
+	if g.body.Moving() {
+		t.Error("body still moving after landing")
+	}
+}
+
+func TestMenuClicks(t *testing.T) {
+	def := config.NewDefault()
+	tests := []struct {
+		name  string
+		row   int
+		outer bool // 点在菜单左边 (宠物身上)
+		err   error
+		check func(t *testing.T, g *Manager, saved *config.Config)
+	}{
+		{name: "color", row: 0, check: func(t *testing.T, g *Manager, saved *config.Config) {
+			if g.ShowColor == def.ShowColor || saved.ShowColor != g.ShowColor {
+				t.Errorf("ShowColor = %v, saved %v, want both %v", g.ShowColor, saved.ShowColor, !def.ShowColor)
+			}
+		}},
+		{name: "hud", row: 1, check: func(t *testing.T, g *Manager, saved *config.Config) {
+			if g.ShowMonitor == def.ShowMonitor || saved.ShowMonitor != g.ShowMonitor {
+				t.Errorf("ShowMonitor = %v, saved %v, want both %v", g.ShowMonitor, saved.ShowMonitor, !def.ShowMonitor)
+			}
+		}},
+		{name: "mode", row: 2, check: func(t *testing.T, g *Manager, saved *config.Config) {
+			if g.DisplayMode != 1 || saved.DisplayMode != 1 {
+				t.Errorf("DisplayMode = %d, saved %d, want both 1", g.DisplayMode, saved.DisplayMode)
+			}
+		}},
+		{name: "theme", row: 4, check: func(t *testing.T, g *Manager, saved *config.Config) {
+			if g.themeName == "" || saved.Theme != g.themeName {
+				t.Errorf("theme = %q, saved %q", g.themeName, saved.Theme)
+			}
+			if want := "theme: " + g.theme.Name; g.MyPet.Bubble != want {
+				t.Errorf("bubble = %q, want %q", g.MyPet.Bubble, want)
+			}
+		}},
+		{name: "exit", row: 5, err: errTerminate},
+		{name: "outside the menu", row: 0, outer: true, check: func(t *testing.T, g *Manager, saved *config.Config) {
+			if saved != nil {
+				t.Error("config saved after a click outside the menu")
+			}
+			if g.ShowColor != def.ShowColor {
+				t.Error("ShowColor toggled by a click outside the menu")
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, win := newTestManager(t, false)
+			if err := play(g, openMenu(250, 250), 0); err != nil {
+				t.Fatal(err)
+			}
+			if !g.ShowMenu {
+				t.Fatal("right click did not open the menu")
+			}
+
+			x, y := menuRow(win, tt.row)
+			if tt.outer {
+				x = win.X + 50
+			}
+			err := play(g, input.Click(x, y, input.ButtonLeft), 2)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Update error = %v, want %v", err, tt.err)
+			}
+			if tt.check == nil {
+				return
+			}
+			// 没有保存过时 saved 为 nil
+			var saved *config.Config
+			if _, err := os.Stat(g.configPath); err == nil {
+				if saved, err = config.Load(g.configPath); err != nil {
+					t.Fatal(err)
+				}
+			}
+			tt.check(t, g, saved)
+		})
+	}
+}
+
+func TestEscapeQuits(t *testing.T) {
+	g, _ := newTestManager(t, false)
+	err := play(g, []input.Frame{{X: 250, Y: 250}, {X: 250, Y: 250, Keys: input.KeyEscape}}, 0)
+	if !errors.Is(err, errTerminate) {
+		t.Fatalf("Update error = %v, want errTerminate", err)
+	}
+}
+
+// TestFrameClock 反应的过期按帧里的时钟算，不看真实时间
+func TestFrameClock(t *testing.T) {
+	g, _ := newTestManager(t, false)
+	frames := []input.Frame{{
+		X: 0, Y: 0,
+		Reactions: []entity.Reaction{{Source: "test", State: entity.StateBusy, Bubble: "hi", Expire: time.Second}},
+	}}
+	if err := play(g, frames, 0); err != nil {
+		t.Fatal(err)
+	}
+	if g.MyPet.State != entity.StateBusy || g.MyPet.Bubble != "hi" {
+		t.Fatalf("state = %q, bubble = %q after the reaction", g.MyPet.State, g.MyPet.Bubble)
+	}
+
+	// 空闲时 TPS 是 8，9 帧刚好超过 1 秒
+	if err := play(g, nil, 9); err != nil {
+		t.Fatal(err)
+	}
+	if g.MyPet.State != entity.StateIdle {
+		t.Errorf("state = %q after the reaction expired, want idle", g.MyPet.State)
+	}
+}
+
+// TestFrameSample 帧里的采样直接决定 TPS 档位
+func TestFrameSample(t *testing.T) {
+	g, win := newTestManager(t, false)
+	battery := &input.Sample{Power: monitor.Power{HasBattery: true, OnBattery: true, Percent: 80}}
+	frames := []input.Frame{{X: 0, Y: 0, Sample: battery}, {X: 0, Y: 0}}
+	if err := play(g, frames, 0); err != nil {
+		t.Fatal(err)
+	}
+	if win.TPS != powerSaveTPS.idle {
+		t.Errorf("TPS = %d on battery, want %d", win.TPS, powerSaveTPS.idle)
+	}
+}
+
+// TestRemotePower 看远程 agent 时，省电档与犯困只看宠物所在机器的电池
+func TestRemotePower(t *testing.T) {
+	g, win := newTestManager(t, false)
+	src, err := remote.NewSource("http://buildbox:9465", remote.ModeJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.source = src
+
+	// 本机插着电源、电量充足；远程机器用电池、快没电了
+	root := t.TempDir()
+	files := map[string]string{"BAT0/type": "Battery", "BAT0/capacity": "90", "BAT0/status": "Charging", "AC/type": "Mains", "AC/online": "1"}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := monitor.PowerSupplyRoot
+	monitor.PowerSupplyRoot = root
+	t.Cleanup(func() { monitor.PowerSupplyRoot = old })
+
+	m := monitor.Metrics{Power: monitor.Power{HasBattery: true, OnBattery: true, Percent: 5}}
+	s := input.Sample{Metrics: m, Power: g.localPower(m)}
+	if s.Power.OnBattery || s.Power.Percent != 90 {
+		t.Fatalf("local power = %+v, want the local plugged-in battery", s.Power)
+	}
+
+	g.checkPower(s)
+	if g.sleepy {
+		t.Error("pet got sleepy from the remote machine's battery")
+	}
+	if err := play(g, []input.Frame{{X: 0, Y: 0, Sample: &s}, {X: 0, Y: 0}}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if win.TPS != normalTPS.idle {
+		t.Errorf("TPS = %d, want %d while the local machine is plugged in", win.TPS, normalTPS.idle)
+	}
+
+	// 本机的电池没电了才犯困
+	s.Power = monitor.Power{HasBattery: true, OnBattery: true, Percent: 5}
+	g.checkPower(s)
+	if !g.sleepy {
+		t.Error("pet is not sleepy on a low local battery")
+	}
+}
+
+// TestRecordReplay 录下一段会话，其间有监控协程送来的采样与反应；回放时没有这些实时来源，窗口轨迹与宠物状态仍然逐帧一致
+func TestRecordReplay(t *testing.T) {
+	type snapshot struct {
+		X, Y   int
+		TPS    int
+		State  string
+		Bubble string
+	}
+	run := func(g *Manager, win *input.FakeWindow, n int) []snapshot {
+		var out []snapshot
+		for i := 0; i < n; i++ {
+			if err := g.Update(); err != nil {
+				t.Fatal(err)
+			}
+			out = append(out, snapshot{win.X, win.Y, win.TPS, g.MyPet.State, g.MyPet.Bubble})
+		}
+		return out
+	}
+
+	// 1. 录制：电池供电 (影响 TPS，也就影响每帧的 dt)、甩出去落地、收到一条告警、在菜单里换主题
+	g, win := newTestManager(t, true)
+	var buf bytes.Buffer
+	g.Recorder = input.NewRecorder(&buf, win)
+	script := g.Input.(*input.Script)
+
+	g.publishSample(input.Sample{Power: monitor.Power{HasBattery: true, OnBattery: true, Percent: 80}})
+	script.Frames = concat(input.Drag(250, 250, 400, 200, 5), input.Hold(400, 200, 0, 100))
+	want := run(g, win, len(script.Frames))
+
+	g.React(entity.Reaction{Source: "rule:cpu", State: entity.StateBusy, Bubble: "cpu high"})
+	script.Frames = append(script.Frames, openMenu(400, 200)...)
+	want = append(want, run(g, win, len(script.Frames)-len(want))...)
+
+	x, y := menuRow(win, 4)
+	script.Frames = append(script.Frames, concat(input.Click(x, y, input.ButtonLeft), input.Hold(x, y, 0, 3))...)
+	want = append(want, run(g, win, len(script.Frames)-len(want))...)
+	if err := g.Recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if last := want[len(want)-1]; last.State != entity.StateBusy || last.Bubble == "cpu high" {
+		t.Fatalf("recording ended in %+v, want the busy state and the theme bubble", last)
+	}
+
+	// 2. 回放
+	rp, err := input.LoadReplay(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g2, _ := newTestManager(t, true)
+	win2 := rp.Window()
+	g2.Input, g2.Window, g2.Replaying = rp, win2, true
+	got := run(g2, win2, len(rp.Frames))
+
+	if len(got) != len(want) {
+		t.Fatalf("replay has %d frames, recording %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("frame %d: replay %+v, recording %+v", i, got[i], want[i])
+		}
+	}
+}
+
+// TestReloadRestartsPlugins 修改自定义指标后按新定义重新采样，不需要重启
+func TestReloadRestartsPlugins(t *testing.T) {
+	g, _ := newTestManager(t, false)
+	dir := t.TempDir()
+	for name, v := range map[string]string{"a": "5", "b": "9"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(v), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reload := func(path string) {
+		t.Helper()
+		file := config.NewDefault()
+		file.CustomMetrics = []config.CustomMetric{{Name: "queue", Type: "file", Path: path, Interval: "10ms"}}
+		g.reloadConfig(file, nil)
+		if g.cfgErr != nil {
+			t.Fatal(g.cfgErr)
+		}
+	}
+	waitQueue := func(want float64) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for g.plugins.Values()["queue"] != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("queue = %v, want %v", g.plugins.Values()["queue"], want)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	t.Cleanup(func() { g.plugins.Stop() })
+
+	reload(filepath.Join(dir, "a"))
+	waitQueue(5)
+	first := g.plugins
+
+	reload(filepath.Join(dir, "b"))
+	if g.plugins == first {
+		t.Fatal("plugins were not restarted after custom_metrics changed")
+	}
+	waitQueue(9)
+}
+
+// TestSwitchProfileRulesOff 从有规则的 profile 切到 "rules": [] 的 profile，规则引擎里就不再有规则
+func TestSwitchProfileRulesOff(t *testing.T) {
+	g, _ := newTestManager(t, false)
+	file := config.NewDefault()
+	file.Profile = "loud"
+	file.Profiles = map[string]config.Profile{
+		"loud":  {Rules: &[]config.RuleConfig{{Name: "cpu", Expr: "cpu > 10"}}},
+		"quiet": {Rules: &[]config.RuleConfig{}},
+	}
+	layers, err := config.Layer(file, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compiled, err := rules.Compile(layers.Effective.Rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.layers, g.cfg, g.ruleEngine = layers, layers.Effective, rules.NewEngine(compiled)
+
+	for _, tt := range []struct {
+		profile string
+		want    int
+	}{{"quiet", 0}, {"loud", 1}, {"quiet", 0}} {
+		g.switchProfile(tt.profile)
+		if g.settings().Profile != tt.profile {
+			t.Fatalf("profile = %q, want %q", g.settings().Profile, tt.profile)
+		}
+		if got := len(g.engine().Rules()); got != tt.want {
+			t.Errorf("profile %q: %d rules, want %d", tt.profile, got, tt.want)
+		}
+	}
+
+	// 存下来的文件里 quiet 仍然是关掉规则，而不是沿用顶层规则
+	saved, err := config.Load(g.configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := saved.Profiles["quiet"].Rules; r == nil || len(*r) != 0 {
+		t.Errorf("saved quiet rules = %v, want []", r)
+	}
+}
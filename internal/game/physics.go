@@ -2,104 +2,145 @@ package game
 
 import (
 	"math"
+	"time"
 
-	"github.com/hajimehoshi/ebiten/v2"
+	"0xPet/internal/entity"
+	"0xPet/internal/input"
+	"0xPet/internal/physics"
+)
+
+// tpsProfile 不同交互状态下的目标 TPS
+type tpsProfile struct {
+	idle, hover, menu, moving int
+}
+
+var (
+	normalTPS    = tpsProfile{idle: 8, hover: 20, menu: 30, moving: 60}
+	powerSaveTPS = tpsProfile{idle: 4, hover: 10, menu: 20, moving: 30} // 电池供电时
 )
 
 // updatePhysics 处理拖拽、惯性滑行、边缘碰撞与 TPS 控制
-func (g *Manager) updatePhysics() {
+func (g *Manager) updatePhysics(in input.Frame) {
 	// 1. 获取当前绝对坐标与尺寸
-	mx, my := ebiten.CursorPosition()
-	wx, wy := ebiten.WindowPosition()
+	mx, my := in.X, in.Y
+	wx, wy := g.Window.Position()
 
 	// 【关键修正】获取实时窗口尺寸 (包含可能已经展开的菜单宽度)
-	ww, wh := ebiten.WindowSize()
+	ww, wh := g.Window.Size()
 
-	isClicking := ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft)
-	isMoving := g.isDragging || math.Abs(g.velX) > 0.1 || math.Abs(g.velY) > 0.1
+	isClicking := in.Pressed(input.ButtonLeft)
+	isMoving := g.isDragging || g.body.Moving()
 
 	// 【关键修正】悬停判定使用实时窗口尺寸 ww, wh
 	isHover := mx >= 0 && mx <= ww && my >= 0 && my <= wh
+	g.isHover = isHover
 
 	// 2. 动态调整 TPS：空闲时最低，鼠标悬停时提升，菜单或滑动时保持流畅
-	targetTPS := 8
+	// 本机用电池供电时自动切换到省电档 (看远程机器时也只看本机的电池)
+	profile := normalTPS
+	if g.power.OnBattery {
+		profile = powerSaveTPS
+	}
+	targetTPS := profile.idle
 	if g.ShowMenu {
-		targetTPS = 30 // 菜单时需要更流畅的响应
+		targetTPS = profile.menu // 菜单时需要更流畅的响应
 	} else if isMoving {
-		targetTPS = 60 // 拖拽或惯性状态时保持流畅
+		targetTPS = profile.moving // 拖拽或惯性状态时保持流畅
 	} else if isHover {
-		targetTPS = 20 // 鼠标悬停时保持适度响应
+		targetTPS = profile.hover // 鼠标悬停时保持适度响应
 	}
 	if targetTPS != g.lastTPS {
-		ebiten.SetTPS(targetTPS)
+		g.Window.SetTPS(targetTPS)
 		g.lastTPS = targetTPS
 	}
 
 	// 3. 拖拽与滑行状态机
+	params := g.physicsParams()
 	if isClicking {
 		// --- 状态 A: 正在被鼠标抓取 ---
 		if !g.isDragging {
 			g.isDragging = true
 			g.dragStartX = mx
 			g.dragStartY = my
+			g.body.Stop()
+			g.tracker.Reset()
+			g.tracker.Add(float64(wx), float64(wy), 0)
 		} else {
 			newX := wx + mx - g.dragStartX
 			newY := wy + my - g.dragStartY
-			ebiten.SetWindowPosition(newX, newY)
+			g.Window.SetPosition(newX, newY)
 
-			// 计算即时脱手速度
-			g.velX = float64(newX - g.lastWinX)
-			g.velY = float64(newY - g.lastWinY)
+			// 【修改】记下拖拽轨迹，松手时用最近一小段估计速度，不再只看最后一帧的位移
+			g.tracker.Add(float64(newX), float64(newY), in.DT)
 		}
 	} else {
-		// --- 状态 B: 松手后的自由滑行 ---
-		g.isDragging = false
-
-		if math.Abs(g.velX) > 0.1 || math.Abs(g.velY) > 0.1 {
-			// 3.1 应用惯性
-			wx += int(g.velX)
-			wy += int(g.velY)
-			ebiten.SetWindowPosition(wx, wy)
-
-			// 3.2 应用摩擦力衰减
-			friction := 0.95
-			g.velX *= friction
-			g.velY *= friction
-
-			// 3.3 屏幕边缘碰撞检测 (反弹)
-			sw, sh := ebiten.ScreenSizeInFullscreen()
-
-			// 左墙
-			if wx < 0 {
-				wx = 0
-				g.velX = -g.velX * 0.6
-			}
-			// 【关键修正】右墙：使用动态窗口宽度 ww
-			if wx+ww > sw {
-				wx = sw - ww
-				g.velX = -g.velX * 0.6
-			}
-			// 上墙
-			if wy < 0 {
-				wy = 0
-				g.velY = -g.velY * 0.6
-			}
-			// 下墙
-			if wy+wh > sh {
-				wy = sh - wh
-				g.velY = -g.velY * 0.6
-			}
+		// --- 状态 B: 松手后的自由滑行 (重力模式下是抛物线下落) ---
+		if g.isDragging {
+			g.isDragging = false
+			g.body.X, g.body.Y = float64(wx), float64(wy)
+			vx, vy := g.tracker.Velocity()
+			g.body.Throw(vx, vy, params)
+		}
 
-			ebiten.SetWindowPosition(wx, wy)
-		} else {
-			// 速度阈值过低直接归零，防止微小抖动
-			g.velX = 0
-			g.velY = 0
+		// 【修改】惯性、摩擦与反弹交给 physics 按固定步长推进，同一次投掷在任何 TPS 下轨迹相同
+		if g.body.Moving() {
+			sw, sh := g.Window.ScreenSize()
+			g.body.W, g.body.H = float64(ww), float64(wh) // 【关键修正】使用动态窗口尺寸 (含展开的菜单)
+			ev := g.body.Advance(in.DT, params, physics.Bounds{W: float64(sw), H: float64(sh)})
+			g.Window.SetPosition(int(math.Round(g.body.X)), int(math.Round(g.body.Y)))
+			if params.Gravity > 0 && ev.Impact > 0 {
+				g.land(ev.Impact)
+			}
 		}
 	}
 
 	// 4. 记录最终位置，供下一帧计算速度增量
-	finalX, finalY := ebiten.WindowPosition()
+	finalX, finalY := g.Window.Position()
 	g.lastWinX = finalX
 	g.lastWinY = finalY
 }
+
+// physicsParams 当前配置里的物理参数 (支持热重载)
+func (g *Manager) physicsParams() physics.Params {
+	cfg := g.settings()
+	p := physics.Params{Friction: cfg.Friction, Restitution: cfg.Restitution, MaxSpeed: cfg.MaxSpeed, Floor: cfg.Floor}
+	if cfg.Gravity {
+		p.Gravity = physics.DefaultGravity
+	}
+	return p
+}
+
+// 落地反应的参数
+const (
+	minSquashImpact = 300.0  // 下落速度 (像素/秒) 低于这个值的轻轻一碰不做反应
+	hardImpact      = 2500.0 // 超过这个值算重重摔了一下
+	maxSquash       = 0.35   // 压扁的最大比例
+	squashDuration  = 350 * time.Millisecond
+	landedExpire    = 2 * time.Second
+)
+
+// land 重力模式下撞到地面：按力度压扁一下，并短暂切换到 landed 状态
+func (g *Manager) land(impact float64) {
+	if impact < minSquashImpact {
+		return
+	}
+	g.landedAt = g.now
+	g.squash = math.Min(impact/hardImpact, 1) * maxSquash
+
+	r := entity.Reaction{Source: "physics", State: entity.StateLanded, Expire: landedExpire}
+	if impact >= hardImpact {
+		r.Bubble = "oof!"
+	}
+	g.React(r)
+}
+
+// squashScale 压扁动画当前的横向与纵向缩放；落地瞬间最扁，随后回弹并稍微拉长一点再恢复
+func (g *Manager) squashScale() (sx, sy float64) {
+	t := g.now.Sub(g.landedAt)
+	if g.squash == 0 || t >= squashDuration {
+		return 1, 1
+	}
+	p := float64(t) / float64(squashDuration)
+	s := g.squash * math.Cos(p*math.Pi*1.5) * (1 - p)
+	return 1 + s, 1 - s
+}
@@ -0,0 +1,70 @@
+package game
+
+import (
+	"strings"
+
+	"0xPet/internal/entity"
+)
+
+// cycleProfile 右键菜单：切换到下一个 profile，最后一个之后回到不使用 profile
+func (g *Manager) cycleProfile() {
+	names := g.layers.File.ProfileNames()
+	if len(names) == 0 {
+		g.React(entity.Reaction{Source: "config", Bubble: "no profiles in config"})
+		return
+	}
+
+	cycle := append([]string{""}, names...)
+	current := g.settings().Profile
+	next := cycle[0]
+	for i, name := range cycle {
+		if name == current {
+			next = cycle[(i+1)%len(cycle)]
+			break
+		}
+	}
+	g.switchProfile(next)
+}
+
+// switchProfile 切换到名为 name 的 profile (空字符串表示不使用)，并记住这次的选择
+func (g *Manager) switchProfile(name string) {
+	// 1. 先把当前 profile 下的改动存好
+	g.saveState()
+
+	// 2. 菜单里的选择优先于启动时的 --profile / OXPET_PROFILE
+	kept := g.Overrides[:0:0]
+	for _, o := range g.Overrides {
+		if o.Key != "profile" {
+			kept = append(kept, o)
+		}
+	}
+	g.Overrides = kept
+
+	// 3. 以文件层为底重新叠加，和热重载走同一条路
+	file := *g.layers.File
+	file.Profile = name
+	g.reloadConfig(&file, nil)
+	if g.settings().Profile != name {
+		return // 新 profile 有错误，reloadConfig 已经弹出了气泡
+	}
+	g.saveState()
+
+	label := name
+	if label == "" {
+		label = "default"
+	}
+	g.React(entity.Reaction{Source: "config", Bubble: "profile: " + label})
+}
+
+// profileLabel 菜单上显示的 profile 名，太长时截断
+func (g *Manager) profileLabel() string {
+	name := g.settings().Profile
+	if name == "" {
+		return "-"
+	}
+	name = strings.ToUpper(name)
+	if len(name) > 8 {
+		name = name[:8]
+	}
+	return name
+}
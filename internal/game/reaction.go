@@ -0,0 +1,239 @@
+package game
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"time"
+
+	"0xPet/internal/entity"
+	"0xPet/internal/exporter"
+	"0xPet/internal/input"
+	"0xPet/internal/monitor"
+	"0xPet/internal/rules"
+)
+
+const (
+	defaultBubbleHold = 6 * time.Second
+	defaultGlitchHold = 1500 * time.Millisecond
+)
+
+// React 提交一个宠物反应，可在任意协程调用；队列满时丢弃，绝不阻塞调用方
+func (g *Manager) React(r entity.Reaction) {
+	select {
+	case g.reactions <- r:
+	default:
+		log.Println("反应队列已满，丢弃:", r.Source)
+	}
+}
+
+// takeReactions 在主循环中取走所有排队的反应，由 stampFrame 随这一帧的输入一起录制
+func (g *Manager) takeReactions() []entity.Reaction {
+	var rs []entity.Reaction
+	for {
+		select {
+		case r := <-g.reactions:
+			rs = append(rs, r)
+		default:
+			return rs
+		}
+	}
+}
+
+// applyReactions 应用这一帧收到的反应，并撤销已经到期的状态
+func (g *Manager) applyReactions(rs []entity.Reaction) {
+	for _, r := range rs {
+		g.applyReaction(r)
+	}
+	g.expireReactions()
+}
+
+// activeReaction 仍然生效的状态类反应
+type activeReaction struct {
+	entity.Reaction
+	until time.Time // 零值表示不会自动过期
+}
+
+func (g *Manager) applyReaction(r entity.Reaction) {
+	now := g.now
+
+	// 1. 同一来源的旧状态先撤掉，新状态追加到末尾 (最后触发的优先显示)
+	kept := g.activeReactions[:0]
+	for _, a := range g.activeReactions {
+		if a.Source != r.Source {
+			kept = append(kept, a)
+		}
+	}
+	g.activeReactions = kept
+	if !r.Clear && (r.State != "" || r.Color != nil) {
+		a := activeReaction{Reaction: r}
+		if r.Expire > 0 {
+			a.until = now.Add(r.Expire)
+		}
+		g.activeReactions = append(g.activeReactions, a)
+	}
+	g.recomputeState()
+
+	// 2. 一次性效果：气泡与乱码爆发
+	if r.Bubble != "" {
+		hold := r.Hold
+		if hold <= 0 {
+			hold = defaultBubbleHold
+		}
+		g.MyPet.Bubble = r.Bubble
+		g.MyPet.BubbleUntil = now.Add(hold)
+	}
+	if r.Glitch {
+		hold := r.Hold
+		if hold <= 0 {
+			hold = defaultGlitchHold
+		}
+		g.MyPet.GlitchUntil = now.Add(hold)
+	}
+}
+
+// expireReactions 撤销已经到期的临时状态
+func (g *Manager) expireReactions() {
+	now := g.now
+	kept := g.activeReactions[:0]
+	for _, a := range g.activeReactions {
+		if a.until.IsZero() || now.Before(a.until) {
+			kept = append(kept, a)
+		}
+	}
+	if len(kept) != len(g.activeReactions) {
+		g.activeReactions = kept
+		g.recomputeState()
+	}
+}
+
+// recomputeState 根据仍然生效的反应重新计算宠物的状态与着色
+func (g *Manager) recomputeState() {
+	state := entity.StateIdle
+	var tint color.Color
+	for _, a := range g.activeReactions {
+		if a.State != "" {
+			state = a.State
+		}
+		if a.Color != nil {
+			tint = a.Color
+		}
+	}
+
+	if tint != g.MyPet.Tint || state != g.MyPet.State {
+		g.isDirty = true
+	}
+	g.MyPet.State = state
+	g.MyPet.Tint = tint
+	g.MyPet.IsStressed = state == entity.StateStressed
+
+	g.statusMu.Lock()
+	g.petStatus.State = state
+	g.petStatus.Stressed = g.MyPet.IsStressed
+	g.statusMu.Unlock()
+
+	// 高压时才启动进程采样，平静时立刻停掉，零额外开销
+	if g.MyPet.IsStressed {
+		g.procSampler.Start()
+	} else {
+		g.procSampler.Stop()
+	}
+}
+
+// evaluateRules 用监控历史评估一次告警规则，把状态变化转成反应
+func (g *Manager) evaluateRules(now time.Time) {
+	engine := g.engine()
+	if engine == nil {
+		return
+	}
+	for _, ev := range engine.Evaluate(g.history, now) {
+		log.Printf("规则 %s: %s (%.1f)", ev.Rule.Name, ev.Status, ev.Value)
+		g.React(ev.Reaction())
+	}
+}
+
+// checkConnection 指标过期时让宠物变灰并提示失联，恢复后撤销 (与 evaluateRules 在同一协程)
+func (g *Manager) checkConnection() {
+	stale := monitor.IsStale()
+	if stale == g.disconnected {
+		return
+	}
+	g.disconnected = stale
+
+	if stale {
+		g.React(entity.Reaction{
+			Source: "monitor:connection",
+			State:  entity.StateDisconnected,
+			Color:  color.RGBA{110, 110, 120, 255},
+			Bubble: "lost contact with " + monitor.SourceName(),
+		})
+	} else {
+		g.React(entity.Reaction{
+			Source: "monitor:connection",
+			Clear:  true,
+			Bubble: "back online: " + monitor.SourceName(),
+		})
+	}
+}
+
+// checkPower 本机低电量时犯困、被监控的机器过热时出汗，恢复后撤销 (与 evaluateRules 在同一协程)；
+// s 是监控协程自己刚取到的采样，不读主循环那一份
+func (g *Manager) checkPower(s input.Sample) {
+	cfg := g.settings()
+
+	sleepy := s.Power.OnBattery && s.Power.Percent < cfg.LowBattery
+	if sleepy != g.sleepy {
+		g.sleepy = sleepy
+		if sleepy {
+			g.React(entity.Reaction{
+				Source: "power:battery",
+				State:  entity.StateSleepy,
+				Color:  color.RGBA{120, 120, 180, 255},
+				Bubble: fmt.Sprintf("battery %.0f%%... so sleepy", s.Power.Percent),
+			})
+		} else {
+			g.React(entity.Reaction{Source: "power:battery", Clear: true})
+		}
+	}
+
+	temp := s.Metrics.MaxTemp()
+	hot := cfg.HotTemp > 0 && temp > cfg.HotTemp
+	if hot != g.hot {
+		g.hot = hot
+		if hot {
+			g.React(entity.Reaction{
+				Source: "power:thermal",
+				State:  entity.StateHot,
+				Color:  color.RGBA{255, 150, 60, 255},
+				Bubble: fmt.Sprintf("%.0f°C, it's hot in here", temp),
+			})
+		} else {
+			g.React(entity.Reaction{Source: "power:thermal", Clear: true})
+		}
+	}
+}
+
+// updateAlertStatus 把规则引擎的生命周期状态同步到导出快照 (与 evaluateRules 在同一协程)
+func (g *Manager) updateAlertStatus() {
+	engine := g.engine()
+	if engine == nil {
+		return
+	}
+	alerts := make([]exporter.AlertStatus, len(engine.Rules()))
+	for i, r := range engine.Rules() {
+		alerts[i] = exporter.AlertStatus{Name: r.Name, Firing: engine.Status(i) == rules.Firing}
+	}
+
+	g.statusMu.Lock()
+	g.petStatus.Alerts = alerts
+	g.statusMu.Unlock()
+}
+
+// PetStatus 返回宠物状态快照，可在任意协程调用
+func (g *Manager) PetStatus() exporter.PetStatus {
+	g.statusMu.Lock()
+	defer g.statusMu.Unlock()
+	st := g.petStatus
+	st.Alerts = append([]exporter.AlertStatus(nil), st.Alerts...)
+	return st
+}
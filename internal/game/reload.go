@@ -0,0 +1,178 @@
+package game
+
+import (
+	"log"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"0xPet/config"
+	"0xPet/internal/entity"
+	"0xPet/internal/monitor"
+	"0xPet/internal/paths"
+	"0xPet/internal/rules"
+)
+
+// configErrorHold 配置错误气泡的显示时长
+const configErrorHold = 15 * time.Second
+
+// configUpdate 监听协程读到的一次配置变更
+type configUpdate struct {
+	cfg *config.Config
+	err error
+}
+
+// queueConfig 配置监听器的回调 (在监听协程里)，交给 Update 处理；队列满了就丢弃，下次修改还会再来
+func (g *Manager) queueConfig(cfg *config.Config, err error) {
+	select {
+	case g.cfgUpdates <- configUpdate{cfg, err}:
+	default:
+	}
+}
+
+// settings 返回当前生效的配置；热重载时整体替换指针，拿到的 *Config 不会再被修改
+func (g *Manager) settings() *config.Config {
+	g.cfgMu.RLock()
+	defer g.cfgMu.RUnlock()
+	return g.cfg
+}
+
+// engine 返回当前的规则引擎
+func (g *Manager) engine() *rules.Engine {
+	g.cfgMu.RLock()
+	defer g.cfgMu.RUnlock()
+	return g.ruleEngine
+}
+
+// applyConfigUpdates 在 Update 中调用，应用所有排队的配置变更
+func (g *Manager) applyConfigUpdates() {
+	for {
+		select {
+		case u := <-g.cfgUpdates:
+			g.reloadConfig(u.cfg, u.err)
+		default:
+			return
+		}
+	}
+}
+
+// reloadConfig 应用一份新配置；有任何错误都整体保留上一份可用的配置，只弹出错误气泡
+func (g *Manager) reloadConfig(cfg *config.Config, err error) {
+	// 1. 文件本身、叠加覆盖项之后的结果或规则写错了
+	var layers *config.Layered
+	var compiled []*rules.Rule
+	if err == nil {
+		layers, err = config.Layer(cfg, g.Overrides)
+	}
+	if err == nil {
+		cfg = layers.Effective
+		compiled, err = rules.Compile(cfg.Rules)
+	}
+	if err != nil {
+		log.Println("配置有误，继续使用上一份配置:", err)
+		g.cfgErr = err
+		g.React(configErrorReaction(err))
+		return
+	}
+
+	old := g.settings()
+	g.layers = layers
+	if g.cfgErr == nil && reflect.DeepEqual(old, cfg) {
+		return // 比如刚刚自己保存的
+	}
+	if g.cfgErr != nil {
+		g.cfgErr = nil
+		g.React(entity.Reaction{Source: "config", Clear: true, Bubble: "config OK"})
+	}
+
+	// 2. 规则变了就换一个新引擎，旧引擎里正在触发的规则先撤销
+	oldEngine := g.engine()
+	engine := oldEngine
+	if !reflect.DeepEqual(old.Rules, cfg.Rules) {
+		engine = rules.NewEngine(compiled)
+		for i, r := range oldEngine.Rules() {
+			if oldEngine.Status(i) == rules.Firing {
+				g.React(entity.Reaction{Source: "rule:" + r.Name, Clear: true})
+			}
+		}
+	}
+
+	g.cfgMu.Lock()
+	g.cfg = cfg
+	g.ruleEngine = engine
+	g.cfgMu.Unlock()
+
+	// 3. 开关类设置立即生效
+	g.ShowColor = cfg.ShowColor
+	g.ShowMonitor = cfg.ShowMonitor
+	g.ShowGlitch = cfg.ShowGlitch
+	g.ShowAnimation = cfg.ShowAnimation
+	g.isDirty = true
+	g.menuDirty = true
+	if cfg.Theme != g.themeName {
+		g.applyTheme(cfg.Theme)
+	}
+
+	// 4. 只有影响字符画转换的设置变了才重新生成图像
+	if cfg.DisplayMode != g.DisplayMode || cfg.Ramp != old.Ramp || !samePath(cfg.ImagePath, old.ImagePath) {
+		g.DisplayMode = cfg.DisplayMode
+		path := paths.ResolveImage(cfg.ImagePath)
+		if path == "" {
+			path = g.currentImgPath
+		}
+		g.LoadPetImage(path)
+	}
+
+	// 5. 自定义指标变了就停掉旧的一组协程，按新定义重新启动
+	if !g.Replaying && !reflect.DeepEqual(old.CustomMetrics, cfg.CustomMetrics) {
+		g.restartPlugins(cfg.CustomMetrics)
+	}
+
+	if changed := restartOnly(old, cfg); len(changed) > 0 {
+		log.Println("以下设置需要重启才能生效:", strings.Join(changed, ", "))
+	}
+	log.Println("配置已重新加载")
+}
+
+// configErrorReaction 配置错误时的气泡；错误信息太长时只保留前一段
+func configErrorReaction(err error) entity.Reaction {
+	msg := err.Error()
+	if len(msg) > 120 {
+		msg = msg[:120] + "..."
+	}
+	return entity.Reaction{Source: "config", Bubble: "config error: " + msg, Hold: configErrorHold}
+}
+
+// restartOnly 列出变化了但要重启才能生效的设置 (监听端口、指标来源、后台采集任务)
+func restartOnly(old, cfg *config.Config) []string {
+	var changed []string
+	check := func(name string, a, b any) {
+		if !reflect.DeepEqual(a, b) {
+			changed = append(changed, name)
+		}
+	}
+	check("metrics_enabled", old.MetricsEnabled, cfg.MetricsEnabled)
+	check("metrics_addr", old.MetricsAddr, cfg.MetricsAddr)
+	check("remote_url", old.RemoteURL, cfg.RemoteURL)
+	check("remote_mode", old.RemoteMode, cfg.RemoteMode)
+	check("cgroup", old.Cgroup, cfg.Cgroup)
+	check("cgroup_root", old.CgroupRoot, cfg.CgroupRoot)
+	check("log_watches", old.LogWatches, cfg.LogWatches)
+	check("process_watches", old.ProcessWatches, cfg.ProcessWatches)
+	check("control_addr", old.ControlAddr, cfg.ControlAddr)
+	return changed
+}
+
+// restartPlugins 用新的自定义指标定义替换正在运行的一组；旧的在后台停止，命令收尾时不会卡住这一帧
+func (g *Manager) restartPlugins(metrics []config.CustomMetric) {
+	old := g.plugins
+	g.plugins = monitor.StartPlugins(pluginSpecs(metrics))
+	monitor.UsePlugins(g.plugins)
+	go old.Stop()
+}
+
+// samePath 比较两个图片路径，忽略分隔符差异
+func samePath(a, b string) bool {
+	return filepath.Clean(a) == filepath.Clean(b)
+}
@@ -1,17 +1,34 @@
+//go:build !headless
+
 package game
 
 import (
-	"fmt"
 	"image"
-	"image/color"
-	"math"
+	"strings"
+
+	"0xPet/internal/entity"
+	"0xPet/internal/hud"
+	"0xPet/internal/monitor"
+	"0xPet/internal/render"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/text"
-	"github.com/hajimehoshi/ebiten/v2/vector"
-	"golang.org/x/image/font"
 )
 
+// canvases 菜单与宠物的离屏画布，嵌在 Manager 里
+type canvases struct {
+	menuCanvas *ebiten.Image
+	petCanvas  *ebiten.Image
+}
+
+func (g *Manager) Draw(screen *ebiten.Image) {
+	if g.menuAnim > 0 {
+		g.drawMenu(screen)
+	}
+	g.drawPet(screen)
+	g.drawJob(ebitenCanvas{screen}, render.HUDHeight)
+	g.drawMood(ebitenCanvas{screen}, render.HUDHeight)
+}
+
 // updatePetCanvas 核心渲染引擎：仅在状态脏化时执行高昂的逐字绘制
 func (g *Manager) updatePetCanvas() {
 	if g.MyPet.Width <= 0 || g.MyPet.Height <= 0 {
@@ -24,53 +41,16 @@ func (g *Manager) updatePetCanvas() {
 	}
 	g.petCanvas.Clear()
 
-	// 2. 解析字体基准
-	var currentFont font.Face
-	var fontW, fontH float64
-	if g.DisplayMode == 0 {
-		currentFont = g.FontNormal
-		fontW, fontH = 8.0, 16.0
-	} else {
-		currentFont = g.FontSmall
-		fontW, fontH = 4.0, 8.0
-	}
-
-	// 3. 将所有字符烤制到 petCanvas 上 (注意：取消了 baseY 偏移，直接从 0,0 开始画)
-	for r, row := range g.MyPet.Grid {
-		for c, charData := range row {
-			x := float64(c) * fontW
-			y := float64(r) * fontH
-
-			var drawColor color.Color
-			if g.MyPet.IsStressed {
-				drawColor = color.RGBA{255, 50, 50, 255}
-			} else if g.ShowColor {
-				rc, gc, bc, ac := charData.Color.RGBA()
-				boost := func(v uint32) uint8 {
-					val := float64(v>>8) * 1.3
-					if val > 255 {
-						return 255
-					}
-					return uint8(val)
-				}
-				drawColor = color.RGBA{boost(rc), boost(gc), boost(bc), uint8(ac >> 8)}
-			} else {
-				drawColor = color.RGBA{0, 255, 0, 255}
-			}
+	// 2. 将所有字符烤制到 petCanvas 上 (注意：取消了 baseY 偏移，直接从 0,0 开始画)
+	render.Pet(ebitenCanvas{g.petCanvas}, g.fonts(), g.MyPet.Grid, render.PetStyle{
+		Mode:      g.DisplayMode,
+		Theme:     g.theme,
+		ShowColor: g.ShowColor,
+		Stressed:  g.MyPet.IsStressed,
+		Tint:      g.MyPet.Tint,
+	})
 
-			r32, g32, b32, _ := drawColor.RGBA()
-			r8, g8, b8 := r32>>8, g32>>8, b32>>8
-			luminance := (r8*299 + g8*587 + b8*114) / 1000
-
-			if luminance > 70 && charData.Char != " " {
-				shadowColor := color.RGBA{0, 0, 0, 140}
-				text.Draw(g.petCanvas, charData.Char, currentFont, int(x)+1, int(y)+1, shadowColor)
-			}
-			text.Draw(g.petCanvas, charData.Char, currentFont, int(x), int(y), drawColor)
-		}
-	}
-
-	// 4. 解除脏标记
+	// 3. 解除脏标记
 	g.isDirty = false
 }
 
@@ -82,82 +62,111 @@ func (g *Manager) drawPet(screen *ebiten.Image) {
 		g.updatePetCanvas()
 	}
 
-	isMoving := g.isDragging || math.Abs(g.velX) > 0.1 || math.Abs(g.velY) > 0.1
+	isMoving := g.isDragging || g.body.Moving()
 
 	// 1. 极致性能：单次 API 调用，把烤好的整张静态宠物贴图拍在屏幕上
 	if g.petCanvas != nil {
 		op := &ebiten.DrawImageOptions{}
-		op.GeoM.Translate(0, 30.0)
+		// 【新增】落地时以底边中点为轴压扁再回弹
+		if sx, sy := g.squashScale(); sx != 1 || sy != 1 {
+			w, h := float64(g.MyPet.Width), float64(g.MyPet.Height)
+			op.GeoM.Translate(-w/2, -h)
+			op.GeoM.Scale(sx, sy)
+			op.GeoM.Translate(w/2, h)
+		}
+		op.GeoM.Translate(0, render.HUDHeight)
 		screen.DrawImage(g.petCanvas, op)
 	}
+	g.drawGlitch(ebitenCanvas{screen}, render.HUDHeight)
 
-	// 2. 独立 HUD 渲染
+	// 2. 独立 HUD 渲染：进度条 + 历史火花线，用小字体塞进顶部 30px 的留白
 	if g.ShowMonitor && !isMoving {
-		msg := fmt.Sprintf("CPU: %.0f%% | MEM: %.0f%%", g.MyPet.CPUUsage, g.MyPet.MemUsage)
-		text.Draw(screen, msg, g.FontNormal, 0, 15, color.RGBA{255, 255, 0, 255})
-	}
-}
-
-func (g *Manager) buildMenuCanvas(height int) {
-	if height <= 0 {
-		return
+		// 顶部留白只放得下 3 行，多出来的自定义指标压在宠物上
+		render.HUD(ebitenCanvas{screen}, g.fonts(), g.hudLines(), g.MyPet.Width, g.theme)
 	}
 
-	if g.menuCanvas == nil || g.menuCanvas.Bounds().Dy() != height {
-		g.menuCanvas = ebiten.NewImage(MenuWidth, height)
+	// 3. 高压时的进程排行面板
+	if g.MyPet.IsStressed && !isMoving {
+		render.ProcPanel(ebitenCanvas{screen}, g.fonts(), g.MyPet.TopProcs, g.isHover, render.HUDHeight, g.MyPet.Width, g.theme)
 	}
-	g.menuCanvas.Clear()
 
-	bgColor := color.RGBA{8, 8, 12, 230}
-	vector.DrawFilledRect(g.menuCanvas, 0, 0, float32(MenuWidth), float32(height), bgColor, false)
-	vector.DrawFilledRect(g.menuCanvas, 0, 0, 2, float32(height), color.RGBA{0, 255, 255, 255}, false)
+	// 4. 规则或事件触发的气泡
+	g.drawBubble(ebitenCanvas{screen}, render.HUDHeight)
+}
 
-	type menuItem struct {
-		label string
-		state bool
+// hudLines 根据监控历史生成 HUD 文本行
+func (g *Manager) hudLines() []string {
+	cols := g.MyPet.Width / 4 // 小字体每个字符 4px 宽
+	if cols < 16 {
+		cols = 16
 	}
-	items := []menuItem{
-		{"COLOR", g.ShowColor},
-		{"HUD", g.ShowMonitor},
-		{"MODE", false},
-		{"EXIT", false},
+	h := monitor.GetHistory()
+	m := g.MyPet.Metrics
+	lines := []string{
+		hud.MeterLine("CPU", m.CPU, h.Values("cpu", cols), cols),
+		hud.MeterLine("MEM", m.Mem, h.Values("mem", cols), cols),
 	}
 
-	baseTextX := 15
-	menuFont := g.FontNormal
+	// 远程监控时第一行显示正在看哪台机器，挤掉 SWAP 行
+	if g.settings().RemoteURL != "" {
+		label := "@" + monitor.SourceName()
+		if g.MyPet.State == entity.StateDisconnected {
+			label += " (disconnected)"
+		}
+		lines = append([]string{label}, lines...)
+	} else {
+		lines = append(lines, hud.MeterLine("SWAP", m.Swap, h.Values("swap", cols), cols))
+	}
+	return append(lines, g.customHUDLines(cols)...)
+}
 
-	for i, item := range items {
-		textY := StartY + i*RowHeight + 18
-		var symbol string
-		var drawCol color.Color = color.RGBA{180, 180, 190, 255}
+// customHUDLines 配置了 hud: true 的自定义指标，出错时显示错误而不是过期的数值
+func (g *Manager) customHUDLines(cols int) []string {
+	statuses := map[string]monitor.PluginStatus{}
+	for _, st := range g.plugins.Statuses() {
+		statuses[st.Name] = st
+	}
 
-		if item.state {
-			symbol = "[*]"
-			drawCol = color.RGBA{0, 255, 255, 255}
-		} else {
-			symbol = "[ ]"
+	var lines []string
+	for _, cm := range g.settings().CustomMetrics {
+		if !cm.HUD {
+			continue
 		}
-
-		if item.label == "MODE" {
-			modes := []string{"NORMAL", "HI-RES", "MINI"}
-			item.label = item.label + ": " + modes[g.DisplayMode]
-			symbol = "[~]"
-			drawCol = color.RGBA{220, 220, 80, 255}
-		} else if item.label == "EXIT" {
-			symbol = "[!]"
-			drawCol = color.RGBA{255, 100, 100, 255} // 警示红
-		} else {
-			if item.state {
-				symbol = "[*]"
-				drawCol = color.RGBA{0, 255, 255, 255} // 高亮青
-			} else {
-				symbol = "[ ]"
+		st := statuses[cm.Name]
+		if !st.OK {
+			msg := st.Err
+			if msg == "" {
+				msg = "waiting"
 			}
+			lines = append(lines, hud.ErrorLine(strings.ToUpper(cm.Name), msg, cols))
+			continue
+		}
+		hi := cm.Max
+		if hi <= 0 {
+			hi = 100
 		}
+		series := monitor.GetHistory().Values(cm.Name, cols)
+		lines = append(lines, hud.ScaledLine(strings.ToUpper(cm.Name), st.Value, hi, series, cols))
+	}
+	return lines
+}
+
+func (g *Manager) buildMenuCanvas(height int) {
+	if height <= 0 {
+		return
+	}
 
-		fullText := fmt.Sprintf("%s %s", symbol, item.label)
-		text.Draw(g.menuCanvas, fullText, menuFont, baseTextX, textY, drawCol)
+	if g.menuCanvas == nil || g.menuCanvas.Bounds().Dy() != height {
+		g.menuCanvas = ebiten.NewImage(MenuWidth, height)
 	}
+	g.menuCanvas.Clear()
+	render.Menu(ebitenCanvas{g.menuCanvas}, g.fonts(), render.MenuState{
+		ShowColor:   g.ShowColor,
+		ShowMonitor: g.ShowMonitor,
+		Mode:        g.DisplayMode,
+		Profile:     g.profileLabel(),
+		Theme:       g.themeLabel(),
+	}, height, g.theme)
 
 	g.menuDirty = false
 }
@@ -0,0 +1,52 @@
+package game
+
+import (
+	"log"
+	"strings"
+
+	"0xPet/internal/entity"
+	"0xPet/internal/theme"
+)
+
+// applyTheme 加载名为 name 的主题 (空字符串表示默认)；找不到或写错时用默认配色，并弹出气泡说明
+func (g *Manager) applyTheme(name string) {
+	t, err := theme.Load(name)
+	if err != nil {
+		log.Println("加载主题失败，使用默认主题:", err)
+		g.React(configErrorReaction(err))
+		t = theme.Default()
+	}
+	g.themeName = name
+	g.theme = t
+	g.isDirty = true
+	g.menuDirty = true
+}
+
+// cycleTheme 右键菜单：切换到下一个主题 (内置主题之后是用户主题目录里的)，并记住这次的选择
+func (g *Manager) cycleTheme() {
+	names := theme.Names()
+	current := g.themeName
+	if current == "" {
+		current = theme.DefaultName
+	}
+	next := names[0]
+	for i, name := range names {
+		if name == current {
+			next = names[(i+1)%len(names)]
+			break
+		}
+	}
+
+	g.applyTheme(next)
+	g.saveState()
+	g.React(entity.Reaction{Source: "config", Bubble: "theme: " + g.theme.Name})
+}
+
+// themeLabel 菜单上显示的主题名，太长时截断
+func (g *Manager) themeLabel() string {
+	name := strings.ToUpper(g.theme.Name)
+	if len(name) > 7 {
+		name = name[:7]
+	}
+	return name
+}
@@ -1,42 +1,42 @@
 package game
 
 import (
-	"os"
-
-	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"0xPet/internal/input"
+	"0xPet/internal/render"
 )
 
+// 菜单布局与 render 包共用，点击判定和绘制的位置才对得上
 const (
-	MenuWidth = 160 // 宽度收紧，足够放下13px的纯文本
-	RowHeight = 25  // 每行的高度，无额外 Gap
-	StartY    = 20  // 顶部留白
-	MinMenuH  = 180 // 菜单的最小安全高度 (20 + 6*25 + 10底部留白)
+	MenuWidth = render.MenuWidth
+	RowHeight = render.MenuRowHeight
+	StartY    = render.MenuStartY
+	MinMenuH  = render.MinMenuH
 )
 
-func (g *Manager) handleUIInput() {
-	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonRight) {
+func (g *Manager) handleUIInput(in input.Frame) {
+	if in.JustPressed(input.ButtonRight) {
 		g.ShowMenu = !g.ShowMenu
-		g.velX, g.velY = 0, 0
+		g.body.Stop()
 		g.menuDirty = true
 	}
 }
 
-func (g *Manager) handleMenuClick() {
-	if !inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
-		return
+// handleMenuClick 处理菜单点击；点了 EXIT 时保存配置并返回 errTerminate
+func (g *Manager) handleMenuClick(in input.Frame) error {
+	if !in.JustPressed(input.ButtonLeft) {
+		return nil
 	}
 
-	mx, my := ebiten.CursorPosition()
+	mx, my := in.X, in.Y
 	menuW := float64(MenuWidth) * g.menuAnim
-	sw, _ := ebiten.WindowSize()
+	sw, _ := g.Window.Size()
 	menuX := float64(sw) - menuW
 	if float64(mx) < menuX {
-		return
+		return nil
 	}
 
 	clickedIdx := -1
-	for i := 0; i < 4; i++ {
+	for i := 0; i < render.MenuItems; i++ {
 		top := StartY + i*RowHeight
 		bot := top + RowHeight
 		if my >= top && my <= bot {
@@ -61,7 +61,14 @@ func (g *Manager) handleMenuClick() {
 		g.saveState()
 		g.LoadPetImage(g.currentImgPath)
 	case 3:
+		g.cycleProfile()
+		g.menuDirty = true
+	case 4:
+		g.cycleTheme()
+		g.menuDirty = true
+	case 5:
 		g.saveState()
-		os.Exit(0)
+		return errTerminate
 	}
+	return nil
 }
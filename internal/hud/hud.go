@@ -0,0 +1,168 @@
+// Package hud provides pure text helpers for building the monitor HUD
+package hud
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// sparkRamp 火花线的字符阶梯，从低到高 (字体里没有方块字符，只能用 ASCII)
+const sparkRamp = "_.-:=+*#"
+
+// Sparkline 把一串数值画成 width 个字符宽的火花线
+// 只取最近的 width 个值，不足时左侧补空格；lo/hi 为纵轴范围
+func Sparkline(values []float64, width int, lo, hi float64) string {
+	if width <= 0 {
+		return ""
+	}
+	if len(values) > width {
+		values = values[len(values)-width:]
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.Repeat(" ", width-len(values)))
+	for _, v := range values {
+		b.WriteByte(sparkRamp[level(v, lo, hi, len(sparkRamp))])
+	}
+	return b.String()
+}
+
+// Bar 画一个宽度为 width 的进度条，比如 "[####----]"
+func Bar(value, lo, hi float64, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	filled := int(math.Round(fraction(value, lo, hi) * float64(width)))
+	return "[" + strings.Repeat("#", filled) + strings.Repeat("-", width-filled) + "]"
+}
+
+// MeterLine 组合一行 HUD：标签 + 百分比 + 进度条 + 火花线，总宽度不超过 cols
+//
+//	CPU  42% [###-----] _.-:=+*#*=
+func MeterLine(label string, value float64, series []float64, cols int) string {
+	head := fmt.Sprintf("%-4s%3.0f%%", label, value)
+	barW := 8
+	if cols < len(head)+barW+3 {
+		return truncate(head, cols)
+	}
+	line := head + " " + Bar(value, 0, 100, barW)
+
+	sparkW := cols - len(line) - 1
+	if sparkW > 0 && len(series) > 0 {
+		line += " " + Sparkline(series, sparkW, 0, 100)
+	}
+	return line
+}
+
+// ScaledLine 和 MeterLine 一样，但用于量程为 [0, hi] 的任意数值 (比如自定义指标)
+//
+//	QUEUE  37 [##------] __.-:=
+func ScaledLine(label string, value, hi float64, series []float64, cols int) string {
+	head := fmt.Sprintf("%-6s%5s", truncate(label, 6), strconv.FormatFloat(value, 'g', 4, 64))
+	barW := 8
+	if cols < len(head)+barW+3 {
+		return truncate(head, cols)
+	}
+	line := head + " " + Bar(value, 0, hi, barW)
+
+	sparkW := cols - len(line) - 1
+	if sparkW > 0 && len(series) > 0 {
+		line += " " + Sparkline(series, sparkW, 0, hi)
+	}
+	return line
+}
+
+// ErrorLine 指标采样出错时的 HUD 行，比如 "QUEUE  ERR 命令超时"
+func ErrorLine(label, msg string, cols int) string {
+	return truncate(fmt.Sprintf("%-6s  ERR %s", truncate(label, 6), msg), cols)
+}
+
+// level 把数值映射到 [0, steps) 的阶梯下标
+func level(v, lo, hi float64, steps int) int {
+	idx := int(fraction(v, lo, hi) * float64(steps-1))
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= steps {
+		idx = steps - 1
+	}
+	return idx
+}
+
+// fraction 把数值归一化到 [0, 1]
+func fraction(v, lo, hi float64) float64 {
+	if hi <= lo || math.IsNaN(v) {
+		return 0
+	}
+	f := (v - lo) / (hi - lo)
+	if f < 0 {
+		return 0
+	}
+	if f > 1 {
+		return 1
+	}
+	return f
+}
+
+func truncate(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// ProcLine 格式化一行进程信息，比如 " 1234 chrome        85%"，总宽度不超过 cols
+func ProcLine(pid int32, name string, percent float64, cols int) string {
+	tail := fmt.Sprintf(" %3.0f%%", percent)
+	head := fmt.Sprintf("%6d ", pid)
+	nameW := cols - len(head) - len(tail)
+	if nameW < 1 {
+		return truncate(head+name, cols)
+	}
+	return head + fmt.Sprintf("%-*s", nameW, truncate(name, nameW)) + tail
+}
+
+// Wrap 按 cols 宽度折行，最多保留 maxLines 行，超出部分用 "..." 收尾
+func Wrap(s string, cols, maxLines int) []string {
+	if cols <= 0 || maxLines <= 0 {
+		return nil
+	}
+	var lines []string
+	for _, para := range strings.Split(s, "\n") {
+		line := ""
+		for _, word := range strings.Fields(para) {
+			for len(word) > cols {
+				if line != "" {
+					lines = append(lines, line)
+					line = ""
+				}
+				lines = append(lines, word[:cols])
+				word = word[cols:]
+			}
+			switch {
+			case line == "":
+				line = word
+			case len(line)+1+len(word) <= cols:
+				line += " " + word
+			default:
+				lines = append(lines, line)
+				line = word
+			}
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) > maxLines {
+		lines = lines[:maxLines]
+		last := lines[maxLines-1]
+		if cols > 3 && len(last) > cols-3 {
+			last = last[:cols-3]
+		}
+		lines[maxLines-1] = last + "..."
+	}
+	return lines
+}
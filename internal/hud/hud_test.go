@@ -0,0 +1,66 @@
+package hud
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		width  int
+		lo, hi float64
+		want   string
+	}{
+		{"阶梯", []float64{0, 15, 29, 43, 58, 72, 86, 100}, 8, 0, 100, "_.-:=+*#"},
+		{"中间值向下取整", []float64{0, 50, 100}, 3, 0, 100, "_:#"},
+		{"左侧补空格", []float64{0, 100}, 5, 0, 100, "   _#"},
+		{"只取最近 width 个", []float64{100, 100, 0, 50, 100}, 3, 0, 100, "_:#"},
+		{"超出量程时截断", []float64{-20, 250}, 2, 0, 100, "_#"},
+		{"按 lo/hi 缩放", []float64{5, 10}, 2, 0, 10, ":#"},
+		{"非零下限", []float64{50, 75, 100}, 3, 50, 100, "_:#"},
+		{"量程无效", []float64{1, 2}, 2, 5, 5, "__"},
+		{"NaN", []float64{math.NaN()}, 1, 0, 100, "_"},
+		{"宽度为 0", []float64{1}, 0, 0, 100, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sparkline(tt.values, tt.width, tt.lo, tt.hi); got != tt.want {
+				t.Errorf("Sparkline(%v, %d, %v, %v) = %q, 期望 %q", tt.values, tt.width, tt.lo, tt.hi, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBar(t *testing.T) {
+	tests := []struct {
+		value, hi float64
+		want      string
+	}{
+		{0, 100, "[--------]"},
+		{50, 100, "[####----]"},
+		{100, 100, "[########]"},
+		{150, 100, "[########]"},
+		{5, 10, "[####----]"},
+	}
+	for _, tt := range tests {
+		if got := Bar(tt.value, 0, tt.hi, 8); got != tt.want {
+			t.Errorf("Bar(%v, 0, %v, 8) = %q, 期望 %q", tt.value, tt.hi, got, tt.want)
+		}
+	}
+}
+
+func TestMeterLine(t *testing.T) {
+	got := MeterLine("CPU", 42, []float64{0, 50, 100}, 24)
+	if want := "CPU  42% [###-----]  _:#"; got != want {
+		t.Errorf("MeterLine = %q, 期望 %q", got, want)
+	}
+	if len(got) > 24 {
+		t.Errorf("宽度 %d 超过 24", len(got))
+	}
+	// 放不下进度条时只保留标签和百分比
+	if got := MeterLine("CPU", 42, nil, 6); got != "CPU  4" {
+		t.Errorf("窄 MeterLine = %q", got)
+	}
+}
@@ -0,0 +1,105 @@
+package input
+
+import "time"
+
+// FakeWindow 内存里的窗口：只记录位置、大小与 TPS，不做任何限制
+type FakeWindow struct {
+	X, Y             int
+	W, H             int
+	ScreenW, ScreenH int
+	TPS              int
+}
+
+var _ Window = (*FakeWindow)(nil)
+
+func (w *FakeWindow) Position() (int, int)   { return w.X, w.Y }
+func (w *FakeWindow) SetPosition(x, y int)   { w.X, w.Y = x, y }
+func (w *FakeWindow) Size() (int, int)       { return w.W, w.H }
+func (w *FakeWindow) SetSize(width, h int)   { w.W, w.H = width, h }
+func (w *FakeWindow) ScreenSize() (int, int) { return w.ScreenW, w.ScreenH }
+func (w *FakeWindow) SetTPS(tps int)         { w.TPS = tps }
+
+// Script 脚本化的输入：依次返回写好的帧，用完后停在最后的光标位置、松开所有按键。
+// 脚本里的光标是屏幕坐标，Poll 时按 Window 的当前位置换算成相对坐标，
+// 这样拖拽时窗口跟着移动，换算出来的相对位置和真实鼠标一样；Just 留空时按上一帧自动推算，
+// DT 留空时按 Window 当前的 TPS 推算 (没有设置 TPS 时按 60)，Time 留空时从 Start 起按 DT 累加
+type Script struct {
+	Window *FakeWindow
+	Frames []Frame
+	Start  time.Time // 脚本时钟的起点
+
+	next int
+	last Frame
+	now  time.Time
+}
+
+var _ Input = (*Script)(nil)
+
+func (s *Script) Poll() Frame {
+	// 放完后的帧不带采样与反应，只保留光标位置
+	f := Frame{X: s.last.X, Y: s.last.Y}
+	if s.next < len(s.Frames) {
+		f = s.Frames[s.next]
+		s.next++
+	}
+	if f.Just == 0 {
+		f.Just = f.Down &^ s.last.Down
+	}
+	s.last = f
+
+	if f.DT == 0 {
+		tps := 60
+		if s.Window != nil && s.Window.TPS > 0 {
+			tps = s.Window.TPS
+		}
+		f.DT = time.Second / time.Duration(tps)
+	}
+	if f.Time.IsZero() {
+		if s.now.IsZero() {
+			s.now = s.Start
+		}
+		f.Time = s.now.Add(f.DT)
+	}
+	s.now = f.Time
+
+	if s.Window != nil {
+		f.X -= s.Window.X
+		f.Y -= s.Window.Y
+	}
+	return f
+}
+
+// Done 脚本是否已经全部播放完
+func (s *Script) Done() bool {
+	return s.next >= len(s.Frames)
+}
+
+// Hold 在屏幕坐标 (x, y) 保持 n 帧，down 是按着的鼠标键
+func Hold(x, y int, down Button, n int) []Frame {
+	frames := make([]Frame, n)
+	for i := range frames {
+		frames[i] = Frame{X: x, Y: y, Down: down}
+	}
+	return frames
+}
+
+// Click 在屏幕坐标 (x, y) 按下再松开鼠标键 b
+func Click(x, y int, b Button) []Frame {
+	return []Frame{{X: x, Y: y, Down: b}, {X: x, Y: y}}
+}
+
+// Drag 按住左键从 (x0, y0) 匀速拖到 (x1, y1)，共 steps 帧，最后一帧松手 (松手前的速度就是甩出去的速度)
+func Drag(x0, y0, x1, y1, steps int) []Frame {
+	if steps < 1 {
+		steps = 1
+	}
+	frames := []Frame{{X: x0, Y: y0, Down: ButtonLeft}}
+	for i := 1; i <= steps; i++ {
+		frames = append(frames, Frame{
+			X:    x0 + (x1-x0)*i/steps,
+			Y:    y0 + (y1-y0)*i/steps,
+			Down: ButtonLeft,
+		})
+	}
+	return append(frames, Frame{X: x1, Y: y1})
+}
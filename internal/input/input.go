@@ -0,0 +1,78 @@
+// Package input provides the per-frame mouse, keyboard and window abstraction the pet runs on,
+// with a scripted fake for deterministic runs and a recorder that saves sessions for exact replay
+package input
+
+import (
+	"time"
+
+	"0xPet/internal/entity"
+	"0xPet/internal/monitor"
+)
+
+// Button 鼠标按键
+type Button uint8
+
+const (
+	ButtonLeft Button = 1 << iota
+	ButtonRight
+)
+
+// Key 用到的键盘按键
+type Key uint8
+
+const (
+	KeyEscape Key = 1 << iota
+)
+
+// File 拖进窗口的文件
+type File struct {
+	Name string `json:"name"`
+	Data []byte `json:"data"`
+}
+
+// Frame 一帧的输入快照；光标坐标相对窗口左上角 (和 ebiten 一样)
+type Frame struct {
+	DT   time.Duration `json:"dt,omitempty"` // 距离上一帧的真实时间，物理按它推进；录下来才能原样回放
+	X    int           `json:"x"`
+	Y    int           `json:"y"`
+	Down Button        `json:"down,omitempty"` // 正按着的鼠标键
+	Just Button        `json:"just,omitempty"` // 这一帧刚按下的鼠标键
+	Keys Key           `json:"keys,omitempty"` // 正按着的键盘按键
+	Drop *File         `json:"drop,omitempty"` // 这一帧拖进来的文件
+
+	// 主循环从其他协程收到的东西也按帧记下来，回放时宠物的反应、动画与 TPS 才和录制时一致
+	Time      time.Time         `json:"time"`                // 这一帧的时钟，主循环里的计时 (反应过期、落地、动画) 都用它；零值表示用当前时间
+	Sample    *Sample           `json:"sample,omitempty"`    // 这一帧拿到的新采样，nil 表示沿用上一次的
+	Reactions []entity.Reaction `json:"reactions,omitempty"` // 这一帧收到的反应 (告警规则、日志、命令结果等)
+}
+
+// Sample 监控协程的一次采样：指标、(高压时才有的) 进程排行与本机供电状态
+type Sample struct {
+	Metrics  monitor.Metrics  `json:"metrics"`
+	TopProcs monitor.TopProcs `json:"top_procs"`
+	Power    monitor.Power    `json:"power"` // 宠物所在机器的供电状态；看远程 agent 时与 Metrics.Power 不是同一台机器
+}
+
+// Pressed 鼠标键 b 是否按着
+func (f Frame) Pressed(b Button) bool { return f.Down&b != 0 }
+
+// JustPressed 鼠标键 b 是否在这一帧刚按下
+func (f Frame) JustPressed(b Button) bool { return f.Just&b != 0 }
+
+// KeyPressed 键盘按键 k 是否按着
+func (f Frame) KeyPressed(k Key) bool { return f.Keys&k != 0 }
+
+// Input 输入来源，每次 Update 开头调用一次 Poll
+type Input interface {
+	Poll() Frame
+}
+
+// Window 宠物窗口与它所在的屏幕
+type Window interface {
+	Position() (x, y int)
+	SetPosition(x, y int)
+	Size() (w, h int)
+	SetSize(w, h int)
+	ScreenSize() (w, h int)
+	SetTPS(tps int) // 每秒 Update 次数，空闲时降频省电
+}
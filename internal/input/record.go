@@ -0,0 +1,122 @@
+package input
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// sessionVersion 录制文件的格式版本 (2: 每帧记录 dt；3: 每帧记录时钟、采样与反应；4: 采样里带本机供电状态)
+const sessionVersion = 4
+
+// Header 录制文件的第一行：开始录制时窗口与屏幕的状态，回放时据此还原
+type Header struct {
+	Version int `json:"version"`
+	WindowX int `json:"window_x"`
+	WindowY int `json:"window_y"`
+	WindowW int `json:"window_w"`
+	WindowH int `json:"window_h"`
+	ScreenW int `json:"screen_w"`
+	ScreenH int `json:"screen_h"`
+}
+
+// Recorder 把主循环每一帧实际用到的输入 (含时钟、采样与反应) 写进录制文件 (JSON Lines：一行头，之后每帧一行)
+type Recorder struct {
+	win    Window
+	w      *bufio.Writer
+	enc    *json.Encoder
+	header bool
+	err    error
+}
+
+// NewRecorder 录制到 w；win 用来在第一帧时记下窗口的初始状态
+func NewRecorder(w io.Writer, win Window) *Recorder {
+	bw := bufio.NewWriter(w)
+	return &Recorder{win: win, w: bw, enc: json.NewEncoder(bw)}
+}
+
+// Record 记录一帧；写失败只记住错误，不影响宠物本身
+func (r *Recorder) Record(f Frame) {
+	// 第一帧时窗口已经摆好了位置，这时再记录初始状态
+	if !r.header {
+		r.header = true
+		h := Header{Version: sessionVersion}
+		h.WindowX, h.WindowY = r.win.Position()
+		h.WindowW, h.WindowH = r.win.Size()
+		h.ScreenW, h.ScreenH = r.win.ScreenSize()
+		r.write(h)
+	}
+	r.write(f)
+}
+
+func (r *Recorder) write(v any) {
+	if r.err == nil {
+		r.err = r.enc.Encode(v)
+	}
+}
+
+// Close 把缓冲的内容写出去，返回录制过程中遇到的第一个错误
+func (r *Recorder) Close() error {
+	if err := r.w.Flush(); r.err == nil {
+		r.err = err
+	}
+	return r.err
+}
+
+// Replay 回放录制文件：按顺序返回录下的帧，放完后停在最后的光标位置、松开所有按键，时钟回到当前时间
+type Replay struct {
+	Header Header
+	Frames []Frame
+
+	next int
+}
+
+var _ Input = (*Replay)(nil)
+
+// LoadReplay 读取 Recorder 写出的录制文件
+func LoadReplay(r io.Reader) (*Replay, error) {
+	dec := json.NewDecoder(r)
+	rp := &Replay{}
+	if err := dec.Decode(&rp.Header); err != nil {
+		return nil, fmt.Errorf("读取录制文件头失败: %w", err)
+	}
+	if rp.Header.Version != sessionVersion {
+		return nil, fmt.Errorf("不支持的录制文件版本 %d", rp.Header.Version)
+	}
+	for {
+		var f Frame
+		err := dec.Decode(&f)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 帧: %w", len(rp.Frames)+1, err)
+		}
+		rp.Frames = append(rp.Frames, f)
+	}
+	return rp, nil
+}
+
+func (r *Replay) Poll() Frame {
+	if r.next < len(r.Frames) {
+		f := r.Frames[r.next]
+		r.next++
+		return f
+	}
+	if n := len(r.Frames); n > 0 {
+		return Frame{X: r.Frames[n-1].X, Y: r.Frames[n-1].Y}
+	}
+	return Frame{}
+}
+
+// Done 是否已经全部播放完
+func (r *Replay) Done() bool {
+	return r.next >= len(r.Frames)
+}
+
+// Window 按录制开始时的状态创建一个内存窗口，用于不开窗口的回放
+func (r *Replay) Window() *FakeWindow {
+	h := r.Header
+	return &FakeWindow{X: h.WindowX, Y: h.WindowY, W: h.WindowW, H: h.WindowH, ScreenW: h.ScreenW, ScreenH: h.ScreenH}
+}
@@ -0,0 +1,282 @@
+// Package logwatch provides log file tailing with regex rules that trigger pet reactions
+package logwatch
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"0xPet/config"
+	"0xPet/internal/entity"
+)
+
+// PollInterval 检查文件变化的间隔
+const PollInterval = 500 * time.Millisecond
+
+// DefaultExpire 日志触发的状态默认保持多久
+const DefaultExpire = 10 * time.Second
+
+// maxLine 单行的最大长度，超出部分直接截断，防止没有换行的大文件撑爆内存
+const maxLine = 64 * 1024
+
+// tailSize 记住 offset 之前的多少字节，用来发现文件被截断后又写回到原来的长度以上
+const tailSize = 64
+
+// Rule 一条匹配规则：正则 + 命中时的反应
+type Rule struct {
+	Pattern  *regexp.Regexp
+	Reaction entity.Reaction // Bubble 可以使用 {match} {line} {1} {2} ... 占位符
+}
+
+// Match 一次命中
+type Match struct {
+	Path   string
+	Line   string
+	Rule   *Rule
+	Groups []string // Groups[0] 是整个匹配，其余是捕获组
+}
+
+// Reaction 把命中翻译成宠物反应，并填充气泡里的占位符
+func (m Match) Reaction() entity.Reaction {
+	r := m.Rule.Reaction
+	if r.Bubble == "" {
+		return r
+	}
+	pairs := []string{"{line}", m.Line}
+	for i, g := range m.Groups {
+		if i == 0 {
+			pairs = append(pairs, "{match}", g)
+		} else {
+			pairs = append(pairs, "{"+strconv.Itoa(i)+"}", g)
+		}
+	}
+	r.Bubble = strings.NewReplacer(pairs...).Replace(r.Bubble)
+	return r
+}
+
+// Watcher 跟踪单个日志文件：只看新写入的行，自动处理轮转 (改名后新建) 与截断 (包括 copytruncate)
+type Watcher struct {
+	path    string
+	rules   []*Rule
+	onMatch func(Match)
+
+	file    *os.File
+	info    os.FileInfo // 当前打开的文件，用于判断是否被轮转
+	offset  int64
+	partial []byte // 还没遇到换行符的半行
+	tail    []byte // offset 之前的最后 tailSize 个字节
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// New 创建一个 watcher；onMatch 在 watcher 自己的协程里调用
+func New(path string, rules []*Rule, onMatch func(Match)) *Watcher {
+	return &Watcher{path: path, rules: rules, onMatch: onMatch, stop: make(chan struct{})}
+}
+
+// Start 开始跟踪：从文件当前末尾开始读，不回放历史内容
+func (w *Watcher) Start() {
+	w.open(true)
+	go w.loop()
+}
+
+// Stop 停止跟踪并关闭文件
+func (w *Watcher) Stop() {
+	w.stopOnce.Do(func() { close(w.stop) })
+}
+
+func (w *Watcher) loop() {
+	ticker := time.NewTicker(PollInterval)
+	defer ticker.Stop()
+	defer w.close()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.poll()
+		}
+	}
+}
+
+// open 打开文件；atEnd 为 true 时跳到末尾 (首次启动)，否则从头读 (轮转后的新文件)
+func (w *Watcher) open(atEnd bool) {
+	f, err := os.Open(w.path)
+	if err != nil {
+		return // 文件还不存在，下一轮再试
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return
+	}
+	w.file, w.info, w.offset, w.partial, w.tail = f, info, 0, nil, nil
+	if atEnd {
+		w.offset = info.Size()
+		w.tail = w.readTail()
+	}
+}
+
+func (w *Watcher) close() {
+	if w.file != nil {
+		w.file.Close()
+		w.file = nil
+	}
+}
+
+// poll 读取新增内容，并检查轮转与截断
+func (w *Watcher) poll() {
+	if w.file == nil {
+		w.open(false)
+		if w.file == nil {
+			return
+		}
+	}
+
+	// 1. 同一个文件被截断了 (copytruncate)：从头开始
+	if w.truncated() {
+		w.offset = 0
+		w.partial = nil
+		w.tail = nil
+	}
+
+	// 2. 把当前文件读完 (轮转前写入旧文件的最后几行也不能丢)
+	w.readNew()
+
+	// 3. 路径指向了另一个文件：轮转，切换到新文件从头读
+	cur, err := os.Stat(w.path)
+	if err != nil {
+		return // 旧文件已被移走、新文件还没创建
+	}
+	if !os.SameFile(cur, w.info) {
+		w.close()
+		w.open(false)
+		w.readNew()
+	}
+}
+
+// truncated 当前打开的文件是否被截断过：比 offset 短，或者 offset 之前的最后几个字节变了
+// (截断之后、下一次检查之前又写入了超过原来长度的内容，只看大小是发现不了的)
+func (w *Watcher) truncated() bool {
+	info, err := w.file.Stat()
+	if err != nil {
+		return false
+	}
+	if info.Size() < w.offset {
+		return true
+	}
+	cur := w.readTail()
+	return w.tail != nil && cur != nil && !bytes.Equal(cur, w.tail)
+}
+
+// readTail 读取 offset 之前的最后 tailSize 个字节，读不到时返回 nil
+func (w *Watcher) readTail() []byte {
+	n := min(w.offset, tailSize)
+	if n == 0 {
+		return nil
+	}
+	buf := make([]byte, n)
+	if _, err := w.file.ReadAt(buf, w.offset-n); err != nil {
+		return nil
+	}
+	return buf
+}
+
+// readNew 从 offset 读到文件末尾，逐行匹配
+func (w *Watcher) readNew() {
+	if _, err := w.file.Seek(w.offset, io.SeekStart); err != nil {
+		return
+	}
+	reader := bufio.NewReader(w.file)
+	for {
+		chunk, err := reader.ReadBytes('\n')
+		w.offset += int64(len(chunk))
+		if len(chunk) > 0 {
+			w.partial = append(w.partial, chunk...)
+			if len(w.partial) > maxLine {
+				w.partial = w.partial[:maxLine]
+			}
+		}
+		if err != nil {
+			w.tail = w.readTail()
+			return // EOF：半行留到下次
+		}
+		line := string(bytes.TrimRight(w.partial, "\r\n"))
+		w.partial = nil
+		w.match(line)
+	}
+}
+
+func (w *Watcher) match(line string) {
+	for _, r := range w.rules {
+		groups := r.Pattern.FindStringSubmatch(line)
+		if groups == nil {
+			continue
+		}
+		w.onMatch(Match{Path: w.path, Line: line, Rule: r, Groups: groups})
+		return // 一行只触发第一条命中的规则
+	}
+}
+
+// Compile 把配置转成可执行的规则
+func Compile(lw config.LogWatch) ([]*Rule, error) {
+	out := make([]*Rule, 0, len(lw.Rules))
+	for i, rc := range lw.Rules {
+		re, err := regexp.Compile(rc.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s 的第 %d 条规则: %w", lw.Path, i+1, err)
+		}
+		r := &Rule{
+			Pattern: re,
+			Reaction: entity.Reaction{
+				Source: "log:" + lw.Path,
+				State:  rc.State,
+				Bubble: rc.Bubble,
+				Glitch: rc.Glitch,
+				Expire: DefaultExpire,
+			},
+		}
+		if rc.Color != "" {
+			c, err := entity.ParseColor(rc.Color)
+			if err != nil {
+				return nil, fmt.Errorf("%s 的第 %d 条规则: %w", lw.Path, i+1, err)
+			}
+			r.Reaction.Color = c
+		}
+		if rc.Duration != "" {
+			d, err := time.ParseDuration(rc.Duration)
+			if err != nil || d < 0 {
+				return nil, fmt.Errorf("%s 的第 %d 条规则: 无效的持续时间 %q", lw.Path, i+1, rc.Duration)
+			}
+			r.Reaction.Expire = d
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// StartAll 为每个配置的日志文件启动 watcher，规则有误的文件会被跳过
+func StartAll(watches []config.LogWatch, onMatch func(Match)) []*Watcher {
+	var watchers []*Watcher
+	for _, lw := range watches {
+		rules, err := Compile(lw)
+		if err != nil {
+			log.Println("日志监听规则有误，跳过:", err)
+			continue
+		}
+		w := New(lw.Path, rules, onMatch)
+		w.Start()
+		watchers = append(watchers, w)
+	}
+	return watchers
+}
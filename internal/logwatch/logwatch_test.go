@@ -0,0 +1,163 @@
+package logwatch
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+
+	"0xPet/config"
+)
+
+// newTestWatcher 跟踪 path 上的 ERROR 行；测试直接调用 poll，不启动后台协程
+func newTestWatcher(t *testing.T, path string) (*Watcher, *[]string) {
+	t.Helper()
+	var lines []string
+	rules := []*Rule{{Pattern: regexp.MustCompile(`ERROR (\w+)`)}}
+	w := New(path, rules, func(m Match) { lines = append(lines, m.Line) })
+	w.open(true)
+	t.Cleanup(w.close)
+	return w, &lines
+}
+
+func write(t *testing.T, path, s string, flag int) {
+	t.Helper()
+	f, err := os.OpenFile(path, flag|os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(s); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func appendTo(t *testing.T, path, s string) { write(t, path, s, os.O_APPEND) }
+
+func check(t *testing.T, got *[]string, want ...string) {
+	t.Helper()
+	if len(*got)+len(want) > 0 && !reflect.DeepEqual(*got, want) {
+		t.Errorf("matched %q, want %q", *got, want)
+	}
+	*got = nil
+}
+
+func TestStartsAtEnd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendTo(t, path, "ERROR old\n")
+	w, got := newTestWatcher(t, path)
+
+	w.poll()
+	check(t, got) // 启动前的内容不回放
+
+	appendTo(t, path, "info fine\nERROR disk\n")
+	w.poll()
+	check(t, got, "ERROR disk")
+}
+
+func TestPartialLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendTo(t, path, "")
+	w, got := newTestWatcher(t, path)
+
+	appendTo(t, path, "ERROR hal")
+	w.poll()
+	check(t, got) // 还没有换行，留到下次
+
+	appendTo(t, path, "f\r\nERROR next")
+	w.poll()
+	check(t, got, "ERROR half")
+
+	appendTo(t, path, "\n")
+	w.poll()
+	check(t, got, "ERROR next")
+}
+
+func TestMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	w, got := newTestWatcher(t, path)
+	w.poll()
+
+	// 启动之后才创建的文件从头读
+	appendTo(t, path, "ERROR first\n")
+	w.poll()
+	check(t, got, "ERROR first")
+}
+
+func TestRenameRotation(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows 上不能改名正在打开的文件")
+	}
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendTo(t, path, "")
+	w, got := newTestWatcher(t, path)
+
+	// 轮转前写入旧文件的最后一行也要读到，然后切换到新文件从头读
+	appendTo(t, path, "ERROR before\n")
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	w.poll()
+	check(t, got, "ERROR before")
+
+	appendTo(t, path, "ERROR after\n")
+	w.poll()
+	check(t, got, "ERROR after")
+
+	// 已经改名的旧文件之后再写入也不会被读到
+	appendTo(t, path+".1", "ERROR stale\n")
+	appendTo(t, path, "ERROR again\n")
+	w.poll()
+	check(t, got, "ERROR again")
+}
+
+func TestTruncate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendTo(t, path, strings.Repeat("info padding line\n", 5))
+	w, got := newTestWatcher(t, path)
+
+	write(t, path, "ERROR short\n", os.O_TRUNC)
+	w.poll()
+	check(t, got, "ERROR short")
+}
+
+// TestCopyTruncate 截断之后、下一次检查之前又写入了比原来更长的内容 (logrotate copytruncate)
+func TestCopyTruncate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendTo(t, path, strings.Repeat("info padding line\n", 5))
+	w, got := newTestWatcher(t, path)
+	appendTo(t, path, "ERROR old\n")
+	w.poll()
+	check(t, got, "ERROR old")
+
+	write(t, path, "ERROR regrown\n"+strings.Repeat("info a much longer padding line\n", 10)+"ERROR tail\n", os.O_TRUNC)
+	w.poll()
+	check(t, got, "ERROR regrown", "ERROR tail")
+
+	// 之后的追加照常读取，不会再从头重复
+	appendTo(t, path, "ERROR later\n")
+	w.poll()
+	check(t, got, "ERROR later")
+}
+
+func TestMatchReaction(t *testing.T) {
+	rules, err := Compile(config.LogWatch{Path: "app.log", Rules: []config.LogRule{
+		{Pattern: `ERROR (\w+)`, Bubble: "{1}: {line}", Color: "#ff0000", Duration: "3s"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := "ERROR disk full"
+	m := Match{Path: "app.log", Line: line, Rule: rules[0], Groups: rules[0].Pattern.FindStringSubmatch(line)}
+	r := m.Reaction()
+	if r.Bubble != "disk: ERROR disk full" || r.Source != "log:app.log" || r.Expire.Seconds() != 3 {
+		t.Errorf("Reaction = %+v", r)
+	}
+
+	if _, err := Compile(config.LogWatch{Path: "x", Rules: []config.LogRule{{Pattern: "("}}}); err == nil {
+		t.Error("invalid pattern compiled")
+	}
+}
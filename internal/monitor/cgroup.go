@@ -0,0 +1,245 @@
+package monitor
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCgroupRoot cgroup v2 的默认挂载点
+const DefaultCgroupRoot = "/sys/fs/cgroup"
+
+// DefaultSelfCgroup 记录本进程所属 cgroup 的文件
+const DefaultSelfCgroup = "/proc/self/cgroup"
+
+// CgroupStats 按本 cgroup 的限额折算后的资源使用情况
+type CgroupStats struct {
+	Path string `json:"path"` // 实际读取的 cgroup 目录
+
+	CPUPercent float64 `json:"cpu_percent"` // 相对 CPU 限额的使用率 (0-100)
+	CPULimit   float64 `json:"cpu_limit"`   // CPU 限额 (核数)，没有限额时为机器核数
+
+	MemCurrent uint64  `json:"mem_current"` // memory.current (字节)
+	MemMax     uint64  `json:"mem_max"`     // memory.max (字节)，0 表示不限
+	MemPercent float64 `json:"mem_percent"` // 相对内存限额的使用率 (0-100)，不限时为 0
+
+	CPUPressure PSI `json:"cpu_pressure"`
+	MemPressure PSI `json:"mem_pressure"`
+	IOPressure  PSI `json:"io_pressure"`
+}
+
+// PSI 压力阻塞信息 (pressure stall information)，单位是百分比
+type PSI struct {
+	SomeAvg10  float64 `json:"some_avg10"`
+	SomeAvg60  float64 `json:"some_avg60"`
+	SomeAvg300 float64 `json:"some_avg300"`
+	FullAvg10  float64 `json:"full_avg10"`
+	FullAvg60  float64 `json:"full_avg60"`
+	FullAvg300 float64 `json:"full_avg300"`
+}
+
+// CgroupSource 在容器或 systemd slice 里运行时使用：CPU 与内存按本 cgroup 的限额折算，
+// 其余指标 (磁盘、网络等) 仍然来自 base
+type CgroupSource struct {
+	dir  string
+	base Source
+
+	mu        sync.Mutex
+	lastUsage uint64 // cpu.stat 里的 usage_usec
+	lastTime  time.Time
+}
+
+// NewCgroupSource 按 selfCgroup (格式同 /proc/self/cgroup) 在 root (cgroup v2 挂载点) 下找到本进程所属的 cgroup；
+// 两者为空时分别用 DefaultCgroupRoot 与 DefaultSelfCgroup，测试时两个都传假的就完全不碰真实系统。
+// 如果 root 下找不到本进程的子目录，就直接读 root 本身
+func NewCgroupSource(root, selfCgroup string, base Source) (*CgroupSource, error) {
+	if root == "" {
+		root = DefaultCgroupRoot
+	}
+	if selfCgroup == "" {
+		selfCgroup = DefaultSelfCgroup
+	}
+
+	dir := root
+	if own, err := ownCgroupPath(selfCgroup); err == nil && own != "/" {
+		candidate := filepath.Join(root, filepath.FromSlash(own))
+		if _, err := os.Stat(filepath.Join(candidate, "cpu.stat")); err == nil {
+			dir = candidate
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "cpu.stat")); err != nil {
+		return nil, fmt.Errorf("%s 不是 cgroup v2 目录: %w", dir, err)
+	}
+	return &CgroupSource{dir: dir, base: base}, nil
+}
+
+// ownCgroupPath 从 /proc/self/cgroup 格式的文件读取本进程的 cgroup v2 路径 ("0::/xxx" 这一行)
+func ownCgroupPath(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if path, ok := strings.CutPrefix(scanner.Text(), "0::"); ok {
+			return path, nil
+		}
+	}
+	return "", errors.New("没有 cgroup v2 记录")
+}
+
+// Name 沿用 base 的名字，并标明是 cgroup 视角
+func (s *CgroupSource) Name() string {
+	return s.base.Name() + " [cgroup]"
+}
+
+// Sample 实现 Source：先取 base 的完整快照，再用 cgroup 数据覆盖 CPU 与内存
+func (s *CgroupSource) Sample() (Metrics, error) {
+	m, err := s.base.Sample()
+	if err != nil {
+		return m, err
+	}
+	cg, err := s.Read(time.Now())
+	if err != nil {
+		return m, err
+	}
+
+	m.Cgroup = &cg
+	m.CPU = cg.CPUPercent
+	if cg.MemMax > 0 {
+		m.Mem = cg.MemPercent
+	}
+	return m, nil
+}
+
+// Read 读取一次 cgroup 文件；CPU 使用率需要两次读取的差值，第一次调用时为 0
+func (s *CgroupSource) Read(now time.Time) (CgroupStats, error) {
+	st := CgroupStats{Path: s.dir}
+
+	// 1. CPU：usage_usec 的增量 / (经过的时间 × 限额核数)
+	stat, err := s.readKeyed("cpu.stat")
+	if err != nil {
+		return st, err
+	}
+	usage := uint64(stat["usage_usec"])
+	st.CPULimit = s.cpuLimit()
+
+	s.mu.Lock()
+	if !s.lastTime.IsZero() && usage >= s.lastUsage {
+		elapsed := now.Sub(s.lastTime).Microseconds()
+		if elapsed > 0 && st.CPULimit > 0 {
+			st.CPUPercent = round1(float64(usage-s.lastUsage) / (float64(elapsed) * st.CPULimit) * 100)
+		}
+	}
+	s.lastUsage, s.lastTime = usage, now
+	s.mu.Unlock()
+	if st.CPUPercent > 100 {
+		st.CPUPercent = 100
+	}
+
+	// 2. 内存：memory.current / memory.max
+	if v, err := s.readUint("memory.current"); err == nil {
+		st.MemCurrent = v
+	}
+	if v, err := s.readUint("memory.max"); err == nil {
+		st.MemMax = v
+	}
+	if st.MemMax > 0 {
+		st.MemPercent = round1(float64(st.MemCurrent) / float64(st.MemMax) * 100)
+	}
+
+	// 3. PSI：内核没开 PSI 时文件不存在，保持 0
+	st.CPUPressure, _ = s.readPSI("cpu.pressure")
+	st.MemPressure, _ = s.readPSI("memory.pressure")
+	st.IOPressure, _ = s.readPSI("io.pressure")
+	return st, nil
+}
+
+// cpuLimit 解析 cpu.max ("<quota> <period>" 或 "max <period>")，没有限额时返回机器核数
+func (s *CgroupSource) cpuLimit() float64 {
+	data, err := os.ReadFile(filepath.Join(s.dir, "cpu.max"))
+	if err == nil {
+		fields := strings.Fields(string(data))
+		if len(fields) == 2 && fields[0] != "max" {
+			quota, err1 := strconv.ParseFloat(fields[0], 64)
+			period, err2 := strconv.ParseFloat(fields[1], 64)
+			if err1 == nil && err2 == nil && period > 0 {
+				return quota / period
+			}
+		}
+	}
+	return float64(runtime.NumCPU())
+}
+
+// readUint 读取单个数字文件，"max" 视为 0 (不限)
+func (s *CgroupSource) readUint(name string) (uint64, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, name))
+	if err != nil {
+		return 0, err
+	}
+	text := strings.TrimSpace(string(data))
+	if text == "max" {
+		return 0, nil
+	}
+	return strconv.ParseUint(text, 10, 64)
+}
+
+// readKeyed 读取 "key value" 逐行格式的文件，比如 cpu.stat
+func (s *CgroupSource) readKeyed(name string) (map[string]float64, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, name))
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]float64{}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if v, err := strconv.ParseFloat(fields[1], 64); err == nil {
+			out[fields[0]] = v
+		}
+	}
+	return out, nil
+}
+
+// readPSI 解析 "some avg10=0.00 avg60=0.00 avg300=0.00 total=0" 格式的压力文件
+func (s *CgroupSource) readPSI(name string) (PSI, error) {
+	var p PSI
+	data, err := os.ReadFile(filepath.Join(s.dir, name))
+	if err != nil {
+		return p, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		vals := map[string]float64{}
+		for _, kv := range fields[1:] {
+			k, v, ok := strings.Cut(kv, "=")
+			if !ok {
+				continue
+			}
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				vals[k] = f
+			}
+		}
+		switch fields[0] {
+		case "some":
+			p.SomeAvg10, p.SomeAvg60, p.SomeAvg300 = vals["avg10"], vals["avg60"], vals["avg300"]
+		case "full":
+			p.FullAvg10, p.FullAvg60, p.FullAvg300 = vals["avg10"], vals["avg60"], vals["avg300"]
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,107 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeFiles 在 dir 下按相对路径写入文件
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// fakeCgroup 一个限额为半个核、512 MiB 内存的 cgroup v2 目录树，本进程在 system.slice/pet.service 里
+func fakeCgroup(t *testing.T) (root, self string) {
+	t.Helper()
+	dir := t.TempDir()
+	root = filepath.Join(dir, "sys/fs/cgroup")
+	self = filepath.Join(dir, "proc/self/cgroup")
+	writeFiles(t, dir, map[string]string{
+		"proc/self/cgroup":                                      "0::/system.slice/pet.service\n",
+		"sys/fs/cgroup/cpu.stat":                                "usage_usec 999999999\n",
+		"sys/fs/cgroup/system.slice/pet.service/cpu.stat":       "usage_usec 1000000\nuser_usec 800000\nsystem_usec 200000\n",
+		"sys/fs/cgroup/system.slice/pet.service/cpu.max":        "50000 100000\n",
+		"sys/fs/cgroup/system.slice/pet.service/memory.current": "268435456\n",
+		"sys/fs/cgroup/system.slice/pet.service/memory.max":     "536870912\n",
+		"sys/fs/cgroup/system.slice/pet.service/cpu.pressure": "some avg10=12.50 avg60=3.00 avg300=1.00 total=123\n" +
+			"full avg10=0.00 avg60=0.00 avg300=0.00 total=0\n",
+		"sys/fs/cgroup/system.slice/pet.service/memory.pressure": "some avg10=1.00 avg60=0.50 avg300=0.10 total=10\n" +
+			"full avg10=0.75 avg60=0.25 avg300=0.05 total=5\n",
+	})
+	return root, self
+}
+
+func TestCgroupSourceQuota(t *testing.T) {
+	root, self := fakeCgroup(t)
+	src, err := NewCgroupSource(root, self, LocalSource())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(root, "system.slice/pet.service"); src.dir != want {
+		t.Fatalf("dir = %q, want %q", src.dir, want)
+	}
+
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	first, err := src.Read(t0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.CPUPercent != 0 {
+		t.Errorf("first CPU = %v, want 0 (no previous reading)", first.CPUPercent)
+	}
+
+	// 两秒内用掉 0.25 秒 CPU，限额半个核 -> 25%
+	writeFiles(t, src.dir, map[string]string{"cpu.stat": "usage_usec 1250000\n"})
+	st, err := src.Read(t0.Add(2 * time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checks := []struct {
+		name      string
+		got, want float64
+	}{
+		{"cpu limit", st.CPULimit, 0.5},
+		{"cpu percent", st.CPUPercent, 25},
+		{"mem percent", st.MemPercent, 50},
+		{"cpu psi some", st.CPUPressure.SomeAvg10, 12.5},
+		{"mem psi full", st.MemPressure.FullAvg10, 0.75},
+		{"io psi (missing file)", st.IOPressure.SomeAvg10, 0},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestCgroupSourceFallsBackToRoot(t *testing.T) {
+	root, self := fakeCgroup(t)
+	writeFiles(t, filepath.Dir(self), map[string]string{"cgroup": "0::/elsewhere.scope\n"})
+
+	src, err := NewCgroupSource(root, self, LocalSource())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src.dir != root {
+		t.Errorf("dir = %q, want root %q", src.dir, root)
+	}
+}
+
+func TestCgroupSourceNotV2(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := NewCgroupSource(dir, filepath.Join(dir, "missing"), LocalSource()); err == nil {
+		t.Error("NewCgroupSource on an empty directory succeeded, want error")
+	}
+}
@@ -0,0 +1,254 @@
+package monitor
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultHistorySize 每个指标保留的样本数 (2 秒一次，约 5 分钟)
+const DefaultHistorySize = 150
+
+// HistorySpan 全局历史大约覆盖的时长
+const HistorySpan = DefaultHistorySize * Interval
+
+// Sample 单个时间点上的指标值
+type Sample struct {
+	Time  time.Time
+	Value float64
+}
+
+// Stats 时间窗口内的统计结果
+type Stats struct {
+	Min   float64
+	Max   float64
+	Avg   float64
+	Count int // 窗口内的样本数，为 0 时其余字段无意义
+}
+
+// Ring 固定容量的环形缓冲区，写满后覆盖最旧的样本
+type Ring struct {
+	buf  []Sample
+	head int // 下一个写入位置
+	size int // 当前有效样本数
+}
+
+// NewRing 创建容量为 capacity 的环形缓冲区
+func NewRing(capacity int) *Ring {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &Ring{buf: make([]Sample, capacity)}
+}
+
+// Push 写入一个新样本
+func (r *Ring) Push(s Sample) {
+	r.buf[r.head] = s
+	r.head = (r.head + 1) % len(r.buf)
+	if r.size < len(r.buf) {
+		r.size++
+	}
+}
+
+// Len 当前有效样本数
+func (r *Ring) Len() int { return r.size }
+
+// Cap 缓冲区容量
+func (r *Ring) Cap() int { return len(r.buf) }
+
+// at 按时间顺序取第 i 个样本 (0 = 最旧)
+func (r *Ring) at(i int) Sample {
+	start := (r.head - r.size + len(r.buf)) % len(r.buf)
+	return r.buf[(start+i)%len(r.buf)]
+}
+
+// Samples 按从旧到新的顺序返回全部样本的副本
+func (r *Ring) Samples() []Sample {
+	out := make([]Sample, r.size)
+	for i := range out {
+		out[i] = r.at(i)
+	}
+	return out
+}
+
+// Last 返回最新的样本
+func (r *Ring) Last() (Sample, bool) {
+	if r.size == 0 {
+		return Sample{}, false
+	}
+	return r.at(r.size - 1), true
+}
+
+// Window 返回 (now-d, now] 内的样本，按从旧到新排列
+func (r *Ring) Window(now time.Time, d time.Duration) []Sample {
+	since := now.Add(-d)
+	var out []Sample
+	for i := 0; i < r.size; i++ {
+		s := r.at(i)
+		if s.Time.After(since) && !s.Time.After(now) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// Stats 统计 (now-d, now] 内的最小/最大/平均值
+func (r *Ring) Stats(now time.Time, d time.Duration) Stats {
+	return summarize(r.Window(now, d))
+}
+
+func summarize(samples []Sample) Stats {
+	if len(samples) == 0 {
+		return Stats{}
+	}
+	st := Stats{Min: samples[0].Value, Max: samples[0].Value, Count: len(samples)}
+	sum := 0.0
+	for _, s := range samples {
+		if s.Value < st.Min {
+			st.Min = s.Value
+		}
+		if s.Value > st.Max {
+			st.Max = s.Value
+		}
+		sum += s.Value
+	}
+	st.Avg = sum / float64(len(samples))
+	return st
+}
+
+// History 按指标名分组保存的环形缓冲区集合，可并发读写
+type History struct {
+	mu       sync.RWMutex
+	capacity int
+	series   map[string]*Ring
+}
+
+// NewHistory 创建每个指标保留 capacity 个样本的历史记录
+func NewHistory(capacity int) *History {
+	return &History{capacity: capacity, series: map[string]*Ring{}}
+}
+
+// Record 把一次采样的所有指标写入各自的缓冲区
+func (h *History) Record(t time.Time, values map[string]float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for key, v := range values {
+		r, ok := h.series[key]
+		if !ok {
+			r = NewRing(h.capacity)
+			h.series[key] = r
+		}
+		r.Push(Sample{Time: t, Value: v})
+	}
+}
+
+// Keys 返回当前已有的指标名
+func (h *History) Keys() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	keys := make([]string, 0, len(h.series))
+	for k := range h.series {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Last 返回某个指标最新的样本
+func (h *History) Last(key string) (Sample, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	r, ok := h.series[key]
+	if !ok {
+		return Sample{}, false
+	}
+	return r.Last()
+}
+
+// Values 返回某个指标最近 n 个值 (从旧到新)，不足 n 个时全部返回
+func (h *History) Values(key string, n int) []float64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	r, ok := h.series[key]
+	if !ok {
+		return nil
+	}
+	if n > r.Len() || n < 0 {
+		n = r.Len()
+	}
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = r.at(r.Len() - n + i).Value
+	}
+	return out
+}
+
+// Window 返回某个指标在 (now-d, now] 内的样本
+func (h *History) Window(key string, now time.Time, d time.Duration) []Sample {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	r, ok := h.series[key]
+	if !ok {
+		return nil
+	}
+	return r.Window(now, d)
+}
+
+// Stats 统计某个指标在 (now-d, now] 内的最小/最大/平均值
+func (h *History) Stats(key string, now time.Time, d time.Duration) Stats {
+	return summarize(h.Window(key, now, d))
+}
+
+// Values 把快照展开成 "指标名 -> 数值"，作为历史记录和规则引擎的统一键空间
+//
+//	cpu, mem, swap, load1, load5, load15, core:N,
+//	disk:<挂载点>, disk_read, disk_write, net:<网卡>:rx, net:<网卡>:tx,
+//	temp (最高温度), battery, on_battery (0/1，只在有电池时出现),
+//	cgroup:cpu, cgroup:mem, psi:cpu, psi:mem, psi:io, psi:mem:full, psi:io:full (使用 cgroup 来源时)，
+//	以及自定义指标的名字 (与内置键重名时内置优先)
+func (m Metrics) Values() map[string]float64 {
+	v := map[string]float64{
+		"cpu":        m.CPU,
+		"mem":        m.Mem,
+		"swap":       m.Swap,
+		"load1":      m.Load1,
+		"load5":      m.Load5,
+		"load15":     m.Load15,
+		"disk_read":  m.DiskRead,
+		"disk_write": m.DiskWrite,
+	}
+	for i, c := range m.PerCore {
+		v["core:"+strconv.Itoa(i)] = c
+	}
+	for _, d := range m.Disks {
+		v["disk:"+d.Mount] = d.UsedPercent
+	}
+	for _, n := range m.Net {
+		v["net:"+n.Name+":rx"] = n.RecvRate
+		v["net:"+n.Name+":tx"] = n.SentRate
+	}
+	if t := m.MaxTemp(); t > 0 {
+		v["temp"] = t
+	}
+	if m.Power.HasBattery {
+		v["battery"] = m.Power.Percent
+		v["on_battery"] = 0
+		if m.Power.OnBattery {
+			v["on_battery"] = 1
+		}
+	}
+	if cg := m.Cgroup; cg != nil {
+		v["cgroup:cpu"] = cg.CPUPercent
+		v["cgroup:mem"] = cg.MemPercent
+		v["psi:cpu"] = cg.CPUPressure.SomeAvg10
+		v["psi:mem"] = cg.MemPressure.SomeAvg10
+		v["psi:io"] = cg.IOPressure.SomeAvg10
+		v["psi:mem:full"] = cg.MemPressure.FullAvg10
+		v["psi:io:full"] = cg.IOPressure.FullAvg10
+	}
+	for k, c := range m.Custom {
+		if _, builtin := v[k]; !builtin {
+			v[k] = c
+		}
+	}
+	return v
+}
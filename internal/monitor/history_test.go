@@ -0,0 +1,156 @@
+package monitor
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func values(samples []Sample) []float64 {
+	out := make([]float64, len(samples))
+	for i, s := range samples {
+		out[i] = s.Value
+	}
+	return out
+}
+
+func TestRingWrap(t *testing.T) {
+	base := time.Unix(1000, 0)
+	r := NewRing(3)
+	if _, ok := r.Last(); ok {
+		t.Fatal("空缓冲区不应有最新样本")
+	}
+	for i := 1; i <= 5; i++ {
+		r.Push(Sample{Time: base.Add(time.Duration(i) * time.Second), Value: float64(i)})
+	}
+	if r.Len() != 3 || r.Cap() != 3 {
+		t.Fatalf("Len/Cap = %d/%d, 期望 3/3", r.Len(), r.Cap())
+	}
+	// 写满后覆盖最旧的样本，顺序仍然从旧到新
+	if got := values(r.Samples()); !reflect.DeepEqual(got, []float64{3, 4, 5}) {
+		t.Errorf("Samples = %v", got)
+	}
+	if last, _ := r.Last(); last.Value != 5 {
+		t.Errorf("Last = %v", last.Value)
+	}
+	if NewRing(0).Cap() != 1 {
+		t.Error("容量小于 1 时应按 1 处理")
+	}
+}
+
+func TestRingWindow(t *testing.T) {
+	base := time.Unix(1000, 0)
+	r := NewRing(10)
+	for i := 0; i < 10; i++ {
+		r.Push(Sample{Time: base.Add(time.Duration(i) * time.Second), Value: float64(i)})
+	}
+	now := base.Add(9 * time.Second)
+
+	// (now-3s, now]：左开右闭
+	if got := values(r.Window(now, 3*time.Second)); !reflect.DeepEqual(got, []float64{7, 8, 9}) {
+		t.Errorf("Window(3s) = %v", got)
+	}
+	// now 之后的样本不算
+	if got := values(r.Window(base.Add(4*time.Second), 2*time.Second)); !reflect.DeepEqual(got, []float64{3, 4}) {
+		t.Errorf("Window(过去的 now) = %v", got)
+	}
+	if got := r.Window(base.Add(time.Hour), time.Second); len(got) != 0 {
+		t.Errorf("窗口里没有样本时应为空，得到 %v", got)
+	}
+
+	st := r.Stats(now, 4*time.Second)
+	if st != (Stats{Min: 6, Max: 9, Avg: 7.5, Count: 4}) {
+		t.Errorf("Stats = %+v", st)
+	}
+	if st := r.Stats(base.Add(time.Hour), time.Second); st.Count != 0 {
+		t.Errorf("空窗口 Stats = %+v", st)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	base := time.Unix(1000, 0)
+	h := NewHistory(4)
+	for i := 0; i < 6; i++ {
+		vals := map[string]float64{"cpu": float64(i * 10)}
+		if i >= 3 {
+			vals["mem"] = float64(i)
+		}
+		h.Record(base.Add(time.Duration(i)*time.Second), vals)
+	}
+
+	keys := h.Keys()
+	sort.Strings(keys)
+	if !reflect.DeepEqual(keys, []string{"cpu", "mem"}) {
+		t.Errorf("Keys = %v", keys)
+	}
+	if got := h.Values("cpu", 2); !reflect.DeepEqual(got, []float64{40, 50}) {
+		t.Errorf("Values(cpu, 2) = %v", got)
+	}
+	// 不足 n 个或 n < 0 时全部返回
+	if got := h.Values("mem", 10); !reflect.DeepEqual(got, []float64{3, 4, 5}) {
+		t.Errorf("Values(mem, 10) = %v", got)
+	}
+	if got := h.Values("cpu", -1); !reflect.DeepEqual(got, []float64{20, 30, 40, 50}) {
+		t.Errorf("Values(cpu, -1) = %v", got)
+	}
+	if got := h.Values("swap", 3); got != nil {
+		t.Errorf("未知指标应返回 nil，得到 %v", got)
+	}
+	if last, ok := h.Last("mem"); !ok || last.Value != 5 || !last.Time.Equal(base.Add(5*time.Second)) {
+		t.Errorf("Last(mem) = %+v, %v", last, ok)
+	}
+	if st := h.Stats("cpu", base.Add(5*time.Second), 2*time.Second); st.Count != 2 || st.Avg != 45 {
+		t.Errorf("Stats(cpu) = %+v", st)
+	}
+}
+
+func TestMetricsValues(t *testing.T) {
+	m := Metrics{
+		CPU: 10, Mem: 20, Swap: 1, Load1: 0.5, Load5: 0.4, Load15: 0.3,
+		PerCore:   []float64{11, 12},
+		Disks:     []DiskUsage{{Mount: "/", UsedPercent: 70}},
+		Net:       []NetIO{{Name: "eth0", RecvRate: 100, SentRate: 50}},
+		DiskRead:  5,
+		DiskWrite: 6,
+		Power:     Power{HasBattery: true, Percent: 80, OnBattery: true},
+		Temps:     []Temp{{Sensor: "cpu", Celsius: 60}, {Sensor: "gpu", Celsius: 72}},
+		Cgroup: &CgroupStats{
+			CPUPercent: 30, MemPercent: 40,
+			CPUPressure: PSI{SomeAvg10: 1},
+			MemPressure: PSI{SomeAvg10: 2, FullAvg10: 3},
+			IOPressure:  PSI{SomeAvg10: 4, FullAvg10: 5},
+		},
+		Custom: map[string]float64{"queue": 7, "cpu": 99},
+	}
+	want := map[string]float64{
+		"cpu": 10, "mem": 20, "swap": 1, "load1": 0.5, "load5": 0.4, "load15": 0.3,
+		"disk_read": 5, "disk_write": 6,
+		"core:0": 11, "core:1": 12,
+		"disk:/":       70,
+		"net:eth0:rx":  100,
+		"net:eth0:tx":  50,
+		"battery":      80,
+		"on_battery":   1,
+		"temp":         72,
+		"cgroup:cpu":   30,
+		"cgroup:mem":   40,
+		"psi:cpu":      1,
+		"psi:mem":      2,
+		"psi:mem:full": 3,
+		"psi:io":       4,
+		"psi:io:full":  5,
+		"queue":        7, // 与内置键重名的 "cpu" 被忽略
+	}
+	if got := m.Values(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Values =\n%v\n期望\n%v", got, want)
+	}
+
+	// 没有电池、温度和 cgroup 时不出现对应的键
+	got := Metrics{CPU: 1}.Values()
+	for _, k := range []string{"battery", "on_battery", "temp", "cgroup:cpu", "psi:cpu"} {
+		if _, ok := got[k]; ok {
+			t.Errorf("不应出现 %q", k)
+		}
+	}
+}
@@ -2,51 +2,373 @@
 package monitor
 
 import (
+	"errors"
+	"log"
 	"math"
+	"os"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/load"
 	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/net"
 )
 
-// 定义两个全局变量存数据
+// Metrics 一次完整采样的快照，HUD 与行为规则都从这里取数
+type Metrics struct {
+	Time time.Time `json:"time"` // 采样时间
+	Host string    `json:"host"` // 被采样机器的主机名
+
+	CPU     float64   `json:"cpu"`      // CPU 总使用率 (0-100)
+	PerCore []float64 `json:"per_core"` // 每个核心的使用率 (0-100)
+	Mem     float64   `json:"mem"`      // 内存 使用率 (0-100)
+	Swap    float64   `json:"swap"`     // 交换分区 使用率 (0-100)
+
+	Load1  float64 `json:"load1"`  // 1 分钟平均负载
+	Load5  float64 `json:"load5"`  // 5 分钟平均负载
+	Load15 float64 `json:"load15"` // 15 分钟平均负载
+
+	Disks     []DiskUsage `json:"disks"`      // 每个挂载点的空间占用
+	DiskRead  float64     `json:"disk_read"`  // 磁盘读吞吐 (字节/秒)
+	DiskWrite float64     `json:"disk_write"` // 磁盘写吞吐 (字节/秒)
+
+	Net []NetIO `json:"net"` // 每块网卡的吞吐
+
+	Custom map[string]float64 `json:"custom,omitempty"` // 用户自定义指标 (只含健康的值)
+
+	Cgroup *CgroupStats `json:"cgroup,omitempty"` // 使用 CgroupSource 时按限额折算的数据
+
+	Power Power  `json:"power"`           // 电池与供电状态
+	Temps []Temp `json:"temps,omitempty"` // 温度传感器读数
+}
+
+// DiskUsage 单个挂载点的空间占用
+type DiskUsage struct {
+	Mount       string  `json:"mount"`        // 挂载点，比如 "/"
+	Total       uint64  `json:"total"`        // 总容量 (字节)
+	Used        uint64  `json:"used"`         // 已用 (字节)
+	UsedPercent float64 `json:"used_percent"` // 使用率 (0-100)
+}
+
+// NetIO 单块网卡的吞吐
+type NetIO struct {
+	Name     string  `json:"name"`      // 网卡名，比如 "eth0"
+	RecvRate float64 `json:"recv_rate"` // 接收速率 (字节/秒)
+	SentRate float64 `json:"sent_rate"` // 发送速率 (字节/秒)
+}
+
+// Source 指标来源：本机、cgroup、远程 agent 等
+type Source interface {
+	Name() string             // 显示在 HUD 上的名字，比如主机名
+	Sample() (Metrics, error) // 采样一次；出错时监控循环保留上一次的数据
+}
+
+// ErrNoNewSample 来源暂时没有新数据 (比如远程 agent 还没采到下一次)；不算失败，只是这一轮不更新快照与历史
+var ErrNoNewSample = errors.New("没有新的采样")
+
+// Interval 采样间隔
+const Interval = 2 * time.Second
+
+// StaleAfter 超过这么久没有成功采样，数据就被视为过期 (比如远程 agent 失联)
+const StaleAfter = 3 * Interval
+
+// 全局最新快照，读写都要经过锁
 var (
-	currentCPU float64
-	currentMem float64
+	mu        sync.RWMutex
+	current   Metrics
+	history   = NewHistory(DefaultHistorySize)
+	source    Source
+	plugins   *Plugins // 合并进每次采样的自定义指标，nil 表示没有
+	startedAt time.Time
+	lastOK    time.Time
+	lastErr   string
 )
 
-// Start 启动监控协程 (只需要在程序启动时调用一次)
+// 计算吞吐用的上一次计数器
+var (
+	lastSample    time.Time
+	lastDiskRead  uint64
+	lastDiskWrite uint64
+	lastNet       = map[string]net.IOCountersStat{}
+)
+
+// Start 用本机数据启动监控协程 (只需要在程序启动时调用一次)
 func Start() {
+	StartSource(LocalSource())
+}
+
+// StartSource 用指定的来源启动监控协程，重复调用只有第一次生效
+func StartSource(src Source) {
+	mu.Lock()
+	if source != nil {
+		mu.Unlock()
+		return
+	}
+	source = src
+	startedAt = time.Now()
+	mu.Unlock()
+
 	// 开启一个 Goroutine (后台线程)
 	go func() {
 		for {
 			updateStats()
 			// 每 2 秒更新一次，避免频繁占用资源
-			time.Sleep(2 * time.Second)
+			time.Sleep(Interval)
 		}
 	}()
 }
 
+// UsePlugins 让监控协程把 p 的最新值并入之后的每次采样；重新加载配置时换成新的一组
+func UsePlugins(p *Plugins) {
+	mu.Lock()
+	plugins = p
+	mu.Unlock()
+}
+
+// SourceName 当前指标来源的名字，未启动时为空
+func SourceName() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	if source == nil {
+		return ""
+	}
+	return source.Name()
+}
+
+// IsStale 数据是否已经过期 (来源连续 StaleAfter 没有成功返回)
+func IsStale() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	if source == nil {
+		return false
+	}
+	last := lastOK
+	if last.IsZero() {
+		last = startedAt
+	}
+	return time.Since(last) > StaleAfter
+}
+
 // GetStats 提供给外部读取数据的方法
 func GetStats() (float64, float64) {
-	return currentCPU, currentMem
+	mu.RLock()
+	defer mu.RUnlock()
+	return current.CPU, current.Mem
+}
+
+// GetMetrics 返回最新一次完整采样的副本
+func GetMetrics() Metrics {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current.clone()
 }
 
-// updateStats 内部逻辑：真正去干活获取数据的函数
+// GetHistory 返回全局指标历史，供 HUD 和规则做时间窗口查询
+func GetHistory() *History {
+	return history
+}
+
+// clone 深拷贝切片字段，防止调用方和采样协程共享底层数组
+func (m Metrics) clone() Metrics {
+	m.PerCore = append([]float64(nil), m.PerCore...)
+	m.Disks = append([]DiskUsage(nil), m.Disks...)
+	m.Net = append([]NetIO(nil), m.Net...)
+	m.Temps = append([]Temp(nil), m.Temps...)
+	m.Power.Batteries = append([]Battery(nil), m.Power.Batteries...)
+	if m.Cgroup != nil {
+		cg := *m.Cgroup
+		m.Cgroup = &cg
+	}
+	if m.Custom != nil {
+		custom := make(map[string]float64, len(m.Custom))
+		for k, v := range m.Custom {
+			custom[k] = v
+		}
+		m.Custom = custom
+	}
+	return m
+}
+
+// updateStats 内部逻辑：从来源取一次数据并写入快照与历史
 func updateStats() {
-	// 1. 获取内存
-	v, err := mem.VirtualMemory()
-	if err == nil {
-		currentMem = v.UsedPercent
+	mu.RLock()
+	src, plug := source, plugins
+	mu.RUnlock()
+
+	m, err := src.Sample()
+	if errors.Is(err, ErrNoNewSample) {
+		return
+	}
+	if err != nil {
+		// 只在错误变化时打日志，避免失联期间刷屏
+		mu.Lock()
+		if err.Error() != lastErr {
+			log.Println("指标采样失败:", src.Name(), err)
+			lastErr = err.Error()
+		}
+		mu.Unlock()
+		return
+	}
+	if m.Time.IsZero() {
+		m.Time = time.Now()
+	}
+	if custom := plug.Values(); len(custom) > 0 {
+		if m.Custom == nil {
+			m.Custom = map[string]float64{}
+		}
+		for k, v := range custom {
+			m.Custom[k] = v
+		}
+	}
+
+	mu.Lock()
+	current = m
+	lastOK = time.Now()
+	lastErr = ""
+	mu.Unlock()
+
+	history.Record(m.Time, m.Values())
+}
+
+// localSource 直接读本机的 gopsutil 数据
+type localSource struct {
+	host string
+}
+
+// LocalSource 返回本机指标来源
+func LocalSource() Source {
+	host, _ := os.Hostname()
+	return &localSource{host: host}
+}
+
+func (s *localSource) Name() string { return s.host }
+
+func (s *localSource) Sample() (Metrics, error) {
+	m := collect()
+	m.Host = s.host
+	return m, nil
+}
+
+// collect 采集一次所有指标，单项失败时保留上一次的值
+func collect() Metrics {
+	mu.RLock()
+	m := current.clone()
+	mu.RUnlock()
+
+	now := time.Now()
+	elapsed := now.Sub(lastSample).Seconds()
+	if lastSample.IsZero() {
+		elapsed = 0
+	}
+	m.Time = now
+	m.Custom = nil // 自定义指标由插件协程单独维护，在 updateStats 里合并
+
+	// 1. 获取内存与交换分区
+	if v, err := mem.VirtualMemory(); err == nil {
+		m.Mem = v.UsedPercent
+	}
+	if s, err := mem.SwapMemory(); err == nil {
+		m.Swap = s.UsedPercent
 	}
 
-	// 2. 获取 CPU
-	c, err := cpu.Percent(0, false)
-	if err == nil && len(c) > 0 {
-		currentCPU = c[0]
+	// 2. 获取 CPU (总量 + 每核)
+	if c, err := cpu.Percent(0, false); err == nil && len(c) > 0 {
+		m.CPU = c[0]
 	}
+	if cores, err := cpu.Percent(0, true); err == nil {
+		m.PerCore = cores
+	}
+
+	// 3. 平均负载 (Windows 上 gopsutil 会自行模拟)
+	if l, err := load.Avg(); err == nil {
+		m.Load1, m.Load5, m.Load15 = l.Load1, l.Load5, l.Load15
+	}
+
+	// 4. 各挂载点空间
+	if parts, err := disk.Partitions(false); err == nil {
+		disks := make([]DiskUsage, 0, len(parts))
+		seen := map[string]bool{}
+		for _, p := range parts {
+			if seen[p.Mountpoint] {
+				continue
+			}
+			seen[p.Mountpoint] = true
+			u, err := disk.Usage(p.Mountpoint)
+			if err != nil || u.Total == 0 {
+				continue
+			}
+			disks = append(disks, DiskUsage{
+				Mount:       p.Mountpoint,
+				Total:       u.Total,
+				Used:        u.Used,
+				UsedPercent: round1(u.UsedPercent),
+			})
+		}
+		sort.Slice(disks, func(i, j int) bool { return disks[i].Mount < disks[j].Mount })
+		m.Disks = disks
+	}
+
+	// 5. 磁盘 I/O 吞吐：累计计数器的差值 / 时间间隔
+	if io, err := disk.IOCounters(); err == nil {
+		var read, write uint64
+		for _, c := range io {
+			read += c.ReadBytes
+			write += c.WriteBytes
+		}
+		if elapsed > 0 {
+			m.DiskRead = rate(read, lastDiskRead, elapsed)
+			m.DiskWrite = rate(write, lastDiskWrite, elapsed)
+		}
+		lastDiskRead, lastDiskWrite = read, write
+	}
+
+	// 6. 每块网卡的吞吐
+	if counters, err := net.IOCounters(true); err == nil {
+		nets := make([]NetIO, 0, len(counters))
+		next := make(map[string]net.IOCountersStat, len(counters))
+		for _, c := range counters {
+			next[c.Name] = c
+			io := NetIO{Name: c.Name}
+			if prev, ok := lastNet[c.Name]; ok && elapsed > 0 {
+				io.RecvRate = rate(c.BytesRecv, prev.BytesRecv, elapsed)
+				io.SentRate = rate(c.BytesSent, prev.BytesSent, elapsed)
+			}
+			nets = append(nets, io)
+		}
+		sort.Slice(nets, func(i, j int) bool { return nets[i].Name < nets[j].Name })
+		m.Net = nets
+		lastNet = next
+	}
+	lastSample = now
+
+	// 7. 电池与温度
+	if p, err := ReadPower(PowerSupplyRoot); err == nil {
+		m.Power = p
+	}
+	m.Temps = readTemps()
 
 	// 这里做个简单的小优化：保留 1 位小数即可，看着干净
-	currentCPU = math.Round(currentCPU*10) / 10
-	currentMem = math.Round(currentMem*10) / 10
+	m.CPU = round1(m.CPU)
+	m.Mem = round1(m.Mem)
+	m.Swap = round1(m.Swap)
+	for i := range m.PerCore {
+		m.PerCore[i] = round1(m.PerCore[i])
+	}
+
+	return m
+}
+
+// rate 计算计数器增速，计数器回绕 (重启/网卡重置) 时按 0 处理
+func rate(cur, prev uint64, seconds float64) float64 {
+	if cur < prev || seconds <= 0 {
+		return 0
+	}
+	return float64(cur-prev) / seconds
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
 }
@@ -0,0 +1,406 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 自定义指标的来源类型
+const (
+	PluginCommand = "command" // 定时执行 shell 命令，解析 stdout
+	PluginFile    = "file"    // 定时读取文件内容
+	PluginSocket  = "socket"  // 从 Unix socket 持续读取 JSON 行
+)
+
+// 自定义指标的默认节奏与保护参数
+const (
+	DefaultPluginInterval = 10 * time.Second
+	DefaultPluginTimeout  = 5 * time.Second
+	maxPluginBackoff      = 5 * time.Minute
+	maxPluginOutput       = 64 * 1024
+)
+
+// PluginSpec 一个用户自定义指标的定义
+type PluginSpec struct {
+	Name     string        // 指标名，也是规则与 HUD 里使用的键
+	Kind     string        // PluginCommand / PluginFile / PluginSocket
+	Command  string        // Kind 为 command 时执行的命令
+	Path     string        // Kind 为 file/socket 时的路径
+	Field    string        // JSON 字段 (支持 a.b 形式)，为空时把整段输出当作数字
+	Interval time.Duration // 采样间隔
+	Timeout  time.Duration // 单次采样的超时
+}
+
+// PluginStatus 自定义指标的运行状态
+type PluginStatus struct {
+	Name     string
+	Value    float64
+	OK       bool      // 最近一次采样是否成功
+	Err      string    // 最近一次错误
+	Updated  time.Time // 最近一次成功的时间
+	Failures int       // 连续失败次数 (决定退避时长)
+}
+
+// Plugins 一组自定义指标的采样协程与各自的最新状态；配置变化时整组 Stop 再按新定义重新启动
+type Plugins struct {
+	mu     sync.RWMutex
+	states map[string]*PluginStatus
+
+	ctx    context.Context // Stop 时取消，结束等待、正在运行的命令和 socket 连接
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// StartPlugins 为每个自定义指标启动独立的采样协程；任何一个卡住或出错都不会影响其他指标和主监控
+func StartPlugins(specs []PluginSpec) *Plugins {
+	p := &Plugins{states: map[string]*PluginStatus{}}
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+
+	for _, spec := range specs {
+		if spec.Interval <= 0 {
+			spec.Interval = DefaultPluginInterval
+		}
+		if spec.Timeout <= 0 {
+			spec.Timeout = DefaultPluginTimeout
+		}
+
+		p.mu.Lock()
+		_, dup := p.states[spec.Name]
+		if !dup {
+			p.states[spec.Name] = &PluginStatus{Name: spec.Name}
+		}
+		p.mu.Unlock()
+		if dup {
+			log.Println("自定义指标重名，忽略:", spec.Name)
+			continue
+		}
+
+		switch spec.Kind {
+		case PluginCommand, PluginFile:
+			p.run(func() { p.poll(spec) })
+		case PluginSocket:
+			p.run(func() { p.stream(spec) })
+		default:
+			p.setError(spec.Name, fmt.Errorf("未知的类型 %q", spec.Kind))
+		}
+	}
+	return p
+}
+
+// run 在协程里执行 f，Stop 会等它返回
+func (p *Plugins) run(f func()) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		f()
+	}()
+}
+
+// Stop 停止所有采样协程并等它们退出；nil 也可以调用
+func (p *Plugins) Stop() {
+	if p == nil {
+		return
+	}
+	p.cancel()
+	p.wg.Wait()
+}
+
+// Values 返回所有健康的自定义指标的最新值
+func (p *Plugins) Values() map[string]float64 {
+	if p == nil {
+		return nil
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	out := make(map[string]float64, len(p.states))
+	for name, st := range p.states {
+		if st.OK {
+			out[name] = st.Value
+		}
+	}
+	return out
+}
+
+// Statuses 返回所有自定义指标的状态，按名字排序
+func (p *Plugins) Statuses() []PluginStatus {
+	if p == nil {
+		return nil
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	out := make([]PluginStatus, 0, len(p.states))
+	for _, st := range p.states {
+		out = append(out, *st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func (p *Plugins) setValue(name string, v float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	st := p.states[name]
+	st.Value = v
+	st.OK = true
+	st.Err = ""
+	st.Updated = time.Now()
+	st.Failures = 0
+}
+
+// setError 记录一次失败并返回连续失败次数
+func (p *Plugins) setError(name string, err error) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	st := p.states[name]
+	if st.Err != err.Error() {
+		log.Printf("自定义指标 %s 采样失败: %v", name, err)
+	}
+	st.OK = false
+	st.Err = err.Error()
+	st.Failures++
+	return st.Failures
+}
+
+// sleep 等待 d，Stop 时提前返回 false
+func (p *Plugins) sleep(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-p.ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// backoff 连续失败时按指数拉长间隔，最长 maxPluginBackoff
+func backoff(interval time.Duration, failures int) time.Duration {
+	d := interval
+	for i := 0; i < failures && d < maxPluginBackoff; i++ {
+		d *= 2
+	}
+	if d > maxPluginBackoff {
+		d = maxPluginBackoff
+	}
+	return d
+}
+
+// poll 命令/文件类指标的采样循环
+func (p *Plugins) poll(spec PluginSpec) {
+	for {
+		v, err := readPlugin(p.ctx, spec)
+		if p.ctx.Err() != nil {
+			return
+		}
+		wait := spec.Interval
+		if err != nil {
+			wait = backoff(spec.Interval, p.setError(spec.Name, err))
+		} else {
+			p.setValue(spec.Name, v)
+		}
+		if !p.sleep(wait) {
+			return
+		}
+	}
+}
+
+// readPlugin 在超时内完成一次读取与解析
+func readPlugin(parent context.Context, spec PluginSpec) (float64, error) {
+	ctx, cancel := context.WithTimeout(parent, spec.Timeout)
+	defer cancel()
+
+	var out []byte
+	var err error
+	if spec.Kind == PluginCommand {
+		out, err = runCommand(ctx, spec.Command)
+	} else {
+		out, err = readFile(ctx, spec.Path)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return ParseValue(out, spec.Field)
+}
+
+// runCommand 通过系统 shell 执行命令；超时会杀掉进程，且不会被残留的子进程卡住；
+// 输出超过 maxPluginOutput 时立刻杀掉进程，不会把刷屏的输出全部攒在内存里
+func runCommand(ctx context.Context, command string) ([]byte, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.WaitDelay = time.Second
+
+	out := &limitedBuffer{max: maxPluginOutput, full: func() { cmd.Process.Kill() }}
+	cmd.Stdout = out
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, errors.New("命令超时")
+	}
+	if out.exceeded {
+		return nil, fmt.Errorf("输出超过 %d 字节", maxPluginOutput)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return out.buf, nil
+}
+
+// errOutputLimit 输出超过上限，让 exec 停止拷贝并关闭管道
+var errOutputLimit = errors.New("输出超过上限")
+
+// limitedBuffer 最多保存 max 字节的 io.Writer；超出时调用 full，之后的写入都返回错误
+type limitedBuffer struct {
+	buf      []byte
+	max      int
+	full     func()
+	exceeded bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.exceeded || len(b.buf)+len(p) > b.max {
+		if !b.exceeded {
+			b.exceeded = true
+			b.full()
+		}
+		return 0, errOutputLimit
+	}
+	b.buf = append(b.buf, p...)
+	return len(p), nil
+}
+
+// readFile 读取文件开头的一段内容；读取放在独立协程里，防止网络盘等卡住采样循环
+func readFile(ctx context.Context, path string) ([]byte, error) {
+	type result struct {
+		data []byte
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		f, err := os.Open(path)
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		defer f.Close()
+		data, err := io.ReadAll(io.LimitReader(f, maxPluginOutput))
+		done <- result{data, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.data, r.err
+	case <-ctx.Done():
+		return nil, errors.New("读取超时")
+	}
+}
+
+// stream socket 类指标：保持连接逐行读取，断开或静默太久就按退避重连
+func (p *Plugins) stream(spec PluginSpec) {
+	for {
+		err := p.readSocket(spec)
+		if p.ctx.Err() != nil {
+			return
+		}
+		if !p.sleep(backoff(spec.Interval, p.setError(spec.Name, err))) {
+			return
+		}
+	}
+}
+
+// readSocket 读取一条连接直到出错；连续 3 个采样间隔没有新数据视为失联，Stop 时关闭连接
+func (p *Plugins) readSocket(spec PluginSpec) error {
+	dialer := net.Dialer{Timeout: spec.Timeout}
+	conn, err := dialer.DialContext(p.ctx, "unix", spec.Path)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(p.ctx, func() { conn.Close() })
+	defer stop()
+
+	scanner := bufio.NewScanner(conn)
+	for {
+		conn.SetReadDeadline(time.Now().Add(3 * spec.Interval))
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return err
+			}
+			return io.EOF
+		}
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		v, err := ParseValue(line, spec.Field)
+		if err != nil {
+			p.setError(spec.Name, err)
+			continue
+		}
+		p.setValue(spec.Name, v)
+	}
+}
+
+// ParseValue 从输出中解析数值：field 为空时输出应是一个数字 (或带 "value" 字段的 JSON)，
+// 否则按 a.b.c 路径从 JSON 对象中取值
+func ParseValue(data []byte, field string) (float64, error) {
+	text := strings.TrimSpace(string(data))
+	if field == "" {
+		if v, err := strconv.ParseFloat(text, 64); err == nil {
+			return v, nil
+		}
+		field = "value"
+	}
+
+	var obj any
+	if err := json.Unmarshal([]byte(text), &obj); err != nil {
+		return 0, fmt.Errorf("无法解析输出: %q", truncateText(text, 40))
+	}
+	for _, key := range strings.Split(field, ".") {
+		m, ok := obj.(map[string]any)
+		if !ok {
+			return 0, fmt.Errorf("字段 %q 不存在", field)
+		}
+		if obj, ok = m[key]; !ok {
+			return 0, fmt.Errorf("字段 %q 不存在", field)
+		}
+	}
+
+	switch v := obj.(type) {
+	case float64:
+		return v, nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("字段 %q 不是数字", field)
+}
+
+func truncateText(s string, n int) string {
+	if len(s) > n {
+		return s[:n] + "..."
+	}
+	return s
+}
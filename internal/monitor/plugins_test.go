@@ -0,0 +1,230 @@
+package monitor
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		field string
+		want  float64
+	}{
+		{"裸数字", " 42.5\n", "", 42.5},
+		{"退回 value 字段", `{"value": 7}`, "", 7},
+		{"嵌套字段", `{"stats": {"queue": {"depth": 12}}}`, "stats.queue.depth", 12},
+		{"布尔 true", `{"up": true}`, "up", 1},
+		{"布尔 false", `{"up": false}`, "up", 0},
+		{"字符串数字", `{"load": " 3.5 "}`, "load", 3.5},
+		{"指定字段时也能解析裸数字", `8`, "", 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseValue([]byte(tt.data), tt.field)
+			if err != nil || got != tt.want {
+				t.Errorf("ParseValue(%q, %q) = %v, %v; 期望 %v", tt.data, tt.field, got, err, tt.want)
+			}
+		})
+	}
+
+	errs := []struct {
+		name  string
+		data  string
+		field string
+		msg   string
+	}{
+		{"不是 JSON", "oops", "", "无法解析"},
+		{"没有 value 字段", `{"count": 1}`, "", `"value" 不存在`},
+		{"路径中间不是对象", `{"stats": 3}`, "stats.depth", "不存在"},
+		{"字段缺失", `{"stats": {}}`, "stats.depth", "不存在"},
+		{"字符串不是数字", `{"v": "high"}`, "v", "不是数字"},
+		{"数组", `{"v": [1]}`, "v", "不是数字"},
+	}
+	for _, tt := range errs {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseValue([]byte(tt.data), tt.field)
+			if err == nil || !strings.Contains(err.Error(), tt.msg) {
+				t.Errorf("ParseValue(%q, %q) 错误 = %v, 期望包含 %q", tt.data, tt.field, err, tt.msg)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		interval time.Duration
+		failures int
+		want     time.Duration
+	}{
+		{10 * time.Second, 0, 10 * time.Second},
+		{10 * time.Second, 1, 20 * time.Second},
+		{10 * time.Second, 3, 80 * time.Second},
+		{10 * time.Second, 5, maxPluginBackoff}, // 320s 超过上限
+		{10 * time.Second, 100, maxPluginBackoff},
+		{10 * time.Minute, 0, maxPluginBackoff}, // 间隔本身就超过上限
+	}
+	for _, tt := range tests {
+		if got := backoff(tt.interval, tt.failures); got != tt.want {
+			t.Errorf("backoff(%v, %d) = %v, 期望 %v", tt.interval, tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestLimitedBuffer(t *testing.T) {
+	calls := 0
+	b := &limitedBuffer{max: 8, full: func() { calls++ }}
+	if n, err := b.Write([]byte("12345")); n != 5 || err != nil {
+		t.Fatalf("Write = %d, %v", n, err)
+	}
+	if n, err := b.Write([]byte("6789")); n != 0 || err != errOutputLimit {
+		t.Fatalf("超限的 Write = %d, %v", n, err)
+	}
+	b.Write([]byte("x"))
+	if calls != 1 || !b.exceeded || string(b.buf) != "12345" {
+		t.Errorf("full 调用 %d 次, exceeded=%v, buf=%q", calls, b.exceeded, b.buf)
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("用到了 sh 命令")
+	}
+
+	out, err := runCommand(context.Background(), "echo 42")
+	if err != nil || strings.TrimSpace(string(out)) != "42" {
+		t.Errorf("runCommand(echo) = %q, %v", out, err)
+	}
+
+	// 无限输出的命令到达上限时被杀掉，而不是一直跑到超时
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	start := time.Now()
+	if _, err := runCommand(ctx, "yes"); err == nil || !strings.Contains(err.Error(), "输出超过") {
+		t.Errorf("runCommand(yes) 错误 = %v", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("到达输出上限后 %v 才返回", d)
+	}
+
+	// 超时杀掉进程，残留的子进程也不会让它一直等下去
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	if _, err := runCommand(ctx, "sleep 10 & sleep 10"); err == nil || err.Error() != "命令超时" {
+		t.Errorf("runCommand(sleep) 错误 = %v", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("超时后 %v 才返回", d)
+	}
+}
+
+// waitFor 轮询 cond 直到成立，超过 5 秒判失败
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待超时: %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestPluginsStop(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "queue")
+	if err := os.WriteFile(path, []byte(`{"depth": 5}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := StartPlugins([]PluginSpec{
+		{Name: "queue", Kind: PluginFile, Path: path, Field: "depth", Interval: 10 * time.Millisecond},
+		{Name: "queue", Kind: PluginFile, Path: "/dup"}, // 重名的被忽略
+		{Name: "sock", Kind: PluginSocket, Path: filepath.Join(dir, "missing.sock"), Interval: 10 * time.Millisecond},
+		{Name: "bogus", Kind: "ftp"},
+	})
+	waitFor(t, "queue 的第一个值", func() bool { return p.Values()["queue"] == 5 })
+
+	st := p.Statuses()
+	if len(st) != 3 || st[0].Name != "bogus" || st[1].Name != "queue" || st[2].Name != "sock" {
+		t.Fatalf("Statuses = %+v", st)
+	}
+	if st[0].OK || !strings.Contains(st[0].Err, "未知的类型") {
+		t.Errorf("bogus = %+v", st[0])
+	}
+
+	// Stop 要等所有协程退出，之后值不再更新
+	done := make(chan struct{})
+	go func() {
+		p.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop 没有返回")
+	}
+	os.WriteFile(path, []byte(`{"depth": 9}`), 0o644)
+	time.Sleep(50 * time.Millisecond)
+	if v := p.Values()["queue"]; v != 5 {
+		t.Errorf("Stop 之后 queue 变成了 %v", v)
+	}
+
+	var none *Plugins
+	none.Stop()
+	if none.Values() != nil || none.Statuses() != nil {
+		t.Error("nil Plugins 应该返回空结果")
+	}
+}
+
+func TestPluginsSocketStop(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("用到了 Unix socket")
+	}
+	path := filepath.Join(t.TempDir(), "m.sock")
+	ln, err := listenUnix(path)
+	if err != nil {
+		t.Skip("无法创建 Unix socket:", err)
+	}
+	defer ln.Close()
+	accepted := make(chan struct{})
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("{\"value\": 3}\n"))
+		close(accepted)
+		buf := make([]byte, 1)
+		conn.Read(buf) // 一直等到对端关闭
+	}()
+
+	p := StartPlugins([]PluginSpec{{Name: "sock", Kind: PluginSocket, Path: path, Interval: time.Hour}})
+	<-accepted
+	waitFor(t, "socket 的第一个值", func() bool { return p.Values()["sock"] == 3 })
+
+	// 读取阻塞在一条静默的连接上，Stop 也要能结束它
+	done := make(chan struct{})
+	go func() {
+		p.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop 没有断开 socket 连接")
+	}
+}
+
+func listenUnix(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,129 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/host"
+)
+
+// PowerSupplyRoot 电源信息的 sysfs 目录，测试时可以指向一个假的目录树
+var PowerSupplyRoot = "/sys/class/power_supply"
+
+// Power 电池与供电状态
+type Power struct {
+	Batteries  []Battery `json:"batteries,omitempty"`
+	HasBattery bool      `json:"has_battery"`
+	OnBattery  bool      `json:"on_battery"` // 是否正在用电池供电 (没有接电源)
+	Percent    float64   `json:"percent"`    // 所有电池的平均电量 (0-100)
+}
+
+// Battery 单块电池
+type Battery struct {
+	Name    string  `json:"name"`    // 比如 "BAT0"
+	Percent float64 `json:"percent"` // 电量 (0-100)
+	Status  string  `json:"status"`  // Charging / Discharging / Full / Not charging / Unknown
+}
+
+// Temp 单个温度传感器
+type Temp struct {
+	Sensor  string  `json:"sensor"`
+	Celsius float64 `json:"celsius"`
+}
+
+// ReadPower 读取 root 下的 power_supply 设备；没有电池的台式机返回零值
+func ReadPower(root string) (Power, error) {
+	var p Power
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return p, nil
+		}
+		return p, err
+	}
+
+	mainsSeen, mainsOnline := false, false
+	for _, e := range entries {
+		dir := filepath.Join(root, e.Name())
+		switch readSysString(dir, "type") {
+		case "Battery":
+			// 有些设备 (比如蓝牙鼠标) 也报告为 Battery，但 scope 是 Device
+			if readSysString(dir, "scope") == "Device" {
+				continue
+			}
+			b := Battery{Name: e.Name(), Status: readSysString(dir, "status")}
+			if v, err := strconv.ParseFloat(readSysString(dir, "capacity"), 64); err == nil {
+				b.Percent = v
+			}
+			p.Batteries = append(p.Batteries, b)
+		case "Mains", "USB", "USB_C":
+			mainsSeen = true
+			if readSysString(dir, "online") == "1" {
+				mainsOnline = true
+			}
+		}
+	}
+	sort.Slice(p.Batteries, func(i, j int) bool { return p.Batteries[i].Name < p.Batteries[j].Name })
+
+	if len(p.Batteries) == 0 {
+		return p, nil
+	}
+	p.HasBattery = true
+	sum := 0.0
+	discharging := false
+	for _, b := range p.Batteries {
+		sum += b.Percent
+		if b.Status == "Discharging" {
+			discharging = true
+		}
+	}
+	p.Percent = round1(sum / float64(len(p.Batteries)))
+
+	// 有交流电源信息时以它为准，否则看电池是不是在放电
+	if mainsSeen {
+		p.OnBattery = !mainsOnline
+	} else {
+		p.OnBattery = discharging
+	}
+	return p, nil
+}
+
+// readSysString 读取 sysfs 属性文件并去掉换行，读取失败返回空字符串
+func readSysString(dir, name string) string {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// readTemps 读取温度传感器；gopsutil 在部分传感器失败时会同时返回数据和错误，有数据就用
+func readTemps() []Temp {
+	stats, err := host.SensorsTemperatures()
+	if err != nil && len(stats) == 0 {
+		return nil
+	}
+	temps := make([]Temp, 0, len(stats))
+	for _, s := range stats {
+		if s.Temperature <= 0 {
+			continue
+		}
+		temps = append(temps, Temp{Sensor: s.SensorKey, Celsius: round1(s.Temperature)})
+	}
+	sort.Slice(temps, func(i, j int) bool { return temps[i].Sensor < temps[j].Sensor })
+	return temps
+}
+
+// MaxTemp 返回最高的传感器温度，没有传感器时为 0
+func (m Metrics) MaxTemp() float64 {
+	max := 0.0
+	for _, t := range m.Temps {
+		if t.Celsius > max {
+			max = t.Celsius
+		}
+	}
+	return max
+}
@@ -0,0 +1,163 @@
+package monitor
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// ProcInfo 单个进程的资源占用
+type ProcInfo struct {
+	PID  int32
+	Name string
+	CPU  float64 // CPU 使用率 (单核满载 = 100)
+	Mem  float64 // 内存占比 (0-100)
+}
+
+// TopProcs 一次进程采样的排行结果
+type TopProcs struct {
+	Time  time.Time
+	ByCPU []ProcInfo
+	ByMem []ProcInfo
+}
+
+// ProcSampler 按需运行的进程采样器：只在 Start 与 Stop 之间工作，平时零开销
+type ProcSampler struct {
+	n        int
+	interval time.Duration
+
+	mu      sync.Mutex
+	stop    chan struct{}
+	latest  TopProcs
+	tracked map[int32]*process.Process // 复用 Process 对象，Percent 才能算出两次采样间的增量
+}
+
+// NewProcSampler 创建一个报告前 n 名进程、每 interval 采样一次的采样器
+func NewProcSampler(n int, interval time.Duration) *ProcSampler {
+	return &ProcSampler{n: n, interval: interval}
+}
+
+// Start 启动采样协程，重复调用无副作用
+func (s *ProcSampler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		return
+	}
+	s.stop = make(chan struct{})
+	s.tracked = map[int32]*process.Process{}
+	go s.loop(s.stop)
+}
+
+// Stop 停止采样并清空结果，重复调用无副作用
+func (s *ProcSampler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	s.stop = nil
+	s.tracked = nil
+	s.latest = TopProcs{}
+}
+
+// Running 采样器当前是否在工作
+func (s *ProcSampler) Running() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stop != nil
+}
+
+// Top 返回最近一次采样的排行
+func (s *ProcSampler) Top() TopProcs {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return TopProcs{
+		Time:  s.latest.Time,
+		ByCPU: append([]ProcInfo(nil), s.latest.ByCPU...),
+		ByMem: append([]ProcInfo(nil), s.latest.ByMem...),
+	}
+}
+
+func (s *ProcSampler) loop(stop chan struct{}) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	s.sample(stop)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.sample(stop)
+		}
+	}
+}
+
+// sample 遍历所有进程并更新排行
+func (s *ProcSampler) sample(stop chan struct{}) {
+	pids, err := process.Pids()
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.stop != stop {
+		// 采样期间已经被 Stop，放弃这一轮
+		s.mu.Unlock()
+		return
+	}
+	tracked := s.tracked
+	s.mu.Unlock()
+
+	alive := make(map[int32]*process.Process, len(pids))
+	infos := make([]ProcInfo, 0, len(pids))
+	for _, pid := range pids {
+		p, ok := tracked[pid]
+		if !ok {
+			if p, err = process.NewProcess(pid); err != nil {
+				continue
+			}
+		}
+		alive[pid] = p
+
+		cpuPct, err := p.Percent(0)
+		if err != nil {
+			continue
+		}
+		memPct, _ := p.MemoryPercent()
+		name, _ := p.Name()
+		infos = append(infos, ProcInfo{
+			PID:  pid,
+			Name: name,
+			CPU:  round1(cpuPct),
+			Mem:  round1(float64(memPct)),
+		})
+	}
+
+	top := TopProcs{
+		Time:  time.Now(),
+		ByCPU: topBy(infos, s.n, func(p ProcInfo) float64 { return p.CPU }),
+		ByMem: topBy(infos, s.n, func(p ProcInfo) float64 { return p.Mem }),
+	}
+
+	s.mu.Lock()
+	if s.stop == stop {
+		s.tracked = alive
+		s.latest = top
+	}
+	s.mu.Unlock()
+}
+
+// topBy 按 key 从大到小取前 n 个
+func topBy(infos []ProcInfo, n int, key func(ProcInfo) float64) []ProcInfo {
+	sorted := append([]ProcInfo(nil), infos...)
+	sort.SliceStable(sorted, func(i, j int) bool { return key(sorted[i]) > key(sorted[j]) })
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
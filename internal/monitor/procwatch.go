@@ -0,0 +1,226 @@
+package monitor
+
+import (
+	"os"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// 进程事件类型
+const (
+	ProcStarted = "started" // 第一次看到匹配的进程
+	ProcLong    = "long"    // 匹配的进程运行时间超过了 BusyAfter
+	ProcExited  = "exited"  // 匹配的进程结束
+)
+
+// ProcPattern 一个需要关注的进程模式
+type ProcPattern struct {
+	Label     string         // 显示名，比如 "cargo"
+	Pattern   *regexp.Regexp // 匹配进程名或完整命令行
+	BusyAfter time.Duration  // 运行超过这么久才算 "长任务"
+}
+
+// ProcEvent 进程生命周期事件
+type ProcEvent struct {
+	Kind      string // ProcStarted / ProcLong / ProcExited
+	Label     string
+	PID       int32
+	Cmdline   string
+	Started   time.Time
+	Duration  time.Duration // 截至事件发生时的运行时长
+	Long      bool          // 是否已经触发过 ProcLong
+	ExitCode  int
+	ExitKnown bool // 退出码是否读得到 (只有在进程变成僵尸时才有机会读到)
+}
+
+// trackedProc 正在跟踪的匹配进程
+type trackedProc struct {
+	pattern  *ProcPattern
+	cmdline  string
+	started  time.Time
+	long     bool
+	exitCode int
+	exitRead bool
+}
+
+// ProcWatcher 周期性扫描进程表，对匹配的进程报告启动与结束
+type ProcWatcher struct {
+	patterns []*ProcPattern
+	interval time.Duration
+	onEvent  func(ProcEvent)
+
+	seen    map[int32]int64 // 已经匹配过的进程：PID -> 启动时间 (毫秒)，PID 被复用时启动时间会变
+	tracked map[int32]*trackedProc
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// NewProcWatcher 创建进程监听器；onEvent 在监听器自己的协程里调用
+func NewProcWatcher(patterns []*ProcPattern, interval time.Duration, onEvent func(ProcEvent)) *ProcWatcher {
+	return &ProcWatcher{
+		patterns: patterns,
+		interval: interval,
+		onEvent:  onEvent,
+		seen:     map[int32]int64{},
+		tracked:  map[int32]*trackedProc{},
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start 开始扫描；启动时已经在运行的匹配进程也会报告 started
+func (w *ProcWatcher) Start() {
+	go func() {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		w.scan()
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+				w.scan()
+			}
+		}
+	}()
+}
+
+// Stop 停止扫描
+func (w *ProcWatcher) Stop() {
+	w.stopOnce.Do(func() { close(w.stop) })
+}
+
+func (w *ProcWatcher) scan() {
+	pids, err := process.Pids()
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	alive := make(map[int32]bool, len(pids))
+	for _, pid := range pids {
+		alive[pid] = true
+	}
+
+	// 1. 已跟踪的进程：消失了就报告结束；变成僵尸时抓紧读退出码
+	for pid, t := range w.tracked {
+		if alive[pid] && !t.exitRead {
+			if code, ok := zombieExitCode(pid); ok {
+				t.exitCode, t.exitRead = code, true
+			}
+		}
+		if alive[pid] && !t.exitRead {
+			if !t.long && t.pattern.BusyAfter > 0 && now.Sub(t.started) >= t.pattern.BusyAfter {
+				t.long = true
+				w.onEvent(w.event(ProcLong, pid, t, now))
+			}
+			continue
+		}
+		// 走到这里说明进程已消失，或者是已经读到退出码的僵尸 (仍留在 seen 里，不会被重复跟踪)
+		ev := w.event(ProcExited, pid, t, now)
+		ev.ExitCode, ev.ExitKnown = t.exitCode, t.exitRead
+		w.onEvent(ev)
+		delete(w.tracked, pid)
+	}
+
+	// 2. 还没匹配过的进程每轮都重新判断：刚 fork 还没 exec 的进程显示的是父进程的命令行，下一轮才看得到真正的命令；
+	// 匹配过的按 (PID, 启动时间) 识别，PID 被新进程复用时重新判断
+	for pid := range w.seen {
+		if !alive[pid] {
+			delete(w.seen, pid)
+		}
+	}
+	for _, pid := range pids {
+		if _, ok := w.tracked[pid]; ok {
+			continue
+		}
+		p, err := process.NewProcess(pid)
+		if err != nil {
+			continue
+		}
+		created, cerr := p.CreateTime()
+		if c, ok := w.seen[pid]; ok {
+			if cerr != nil || c == created {
+				continue
+			}
+			delete(w.seen, pid)
+		}
+
+		name, _ := p.Name()
+		cmdline, _ := p.Cmdline()
+		pat := w.match(name, cmdline)
+		if pat == nil {
+			continue
+		}
+		w.seen[pid] = created
+
+		started := now
+		if cerr == nil {
+			started = time.UnixMilli(created)
+		}
+		if cmdline == "" {
+			cmdline = name
+		}
+		t := &trackedProc{pattern: pat, cmdline: cmdline, started: started}
+		w.tracked[pid] = t
+		w.onEvent(w.event(ProcStarted, pid, t, now))
+	}
+}
+
+// match 返回第一个匹配进程名或命令行的模式
+func (w *ProcWatcher) match(name, cmdline string) *ProcPattern {
+	for _, p := range w.patterns {
+		if p.Pattern.MatchString(name) || (cmdline != "" && p.Pattern.MatchString(cmdline)) {
+			return p
+		}
+	}
+	return nil
+}
+
+func (w *ProcWatcher) event(kind string, pid int32, t *trackedProc, now time.Time) ProcEvent {
+	return ProcEvent{
+		Kind:     kind,
+		Label:    t.pattern.Label,
+		PID:      pid,
+		Cmdline:  t.cmdline,
+		Started:  t.started,
+		Duration: now.Sub(t.started),
+		Long:     t.long,
+	}
+}
+
+// zombieExitCode 在 Linux 上读取僵尸进程 /proc/<pid>/stat 的 exit_code 字段 (第 52 个)
+func zombieExitCode(pid int32) (int, bool) {
+	if runtime.GOOS != "linux" {
+		return 0, false
+	}
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(int(pid)) + "/stat")
+	if err != nil {
+		return 0, false
+	}
+	// comm 字段可能包含空格，从最后一个 ')' 之后开始切分
+	s := string(data)
+	end := strings.LastIndexByte(s, ')')
+	if end < 0 {
+		return 0, false
+	}
+	fields := strings.Fields(s[end+1:])
+	// fields[0] 是第 3 个字段 (state)，exit_code 是第 52 个
+	if len(fields) < 50 || fields[0] != "Z" {
+		return 0, false
+	}
+	status, err := strconv.Atoi(fields[49])
+	if err != nil {
+		return 0, false
+	}
+	// 与 waitpid 的 status 编码相同：正常退出时高 8 位是退出码，被信号杀死时低 7 位是信号
+	if status&0x7f != 0 {
+		return 128 + status&0x7f, true
+	}
+	return (status >> 8) & 0xff, true
+}
@@ -0,0 +1,181 @@
+// Package paths provides the on-disk locations used by 0xPet: config, user data, caches and bundled assets
+package paths
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// AppName 各个目录下的子目录名
+const AppName = "0xpet"
+
+// 文件名
+const (
+	ConfigName   = "config.json"
+	SavedPetName = "saved_pet.png"
+	AssetsDir    = "assets"
+	ThemesName   = "themes"
+)
+
+// ConfigDir 配置目录：$XDG_CONFIG_HOME/0xpet (Windows 是 %AppData%\0xpet，macOS 是 ~/Library/Application Support/0xpet)；
+// 连用户目录都拿不到时退回当前目录
+func ConfigDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "."
+	}
+	return filepath.Join(dir, AppName)
+}
+
+// DataDir 用户数据目录 (拖进来的宠物图片)：$XDG_DATA_HOME/0xpet，默认 ~/.local/share/0xpet；
+// Windows 与 macOS 没有单独的数据目录，和配置放在一起
+func DataDir() string {
+	if dir := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, AppName)
+	}
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		return ConfigDir()
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "."
+	}
+	return filepath.Join(home, ".local", "share", AppName)
+}
+
+// CacheDir 缓存目录 (字符画转换结果等，删掉也没关系)：$XDG_CACHE_HOME/0xpet，默认 ~/.cache/0xpet
+func CacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), AppName)
+	}
+	return filepath.Join(dir, AppName)
+}
+
+// ConfigFile 配置文件的完整路径
+func ConfigFile() string {
+	return filepath.Join(ConfigDir(), ConfigName)
+}
+
+// ThemesDir 用户主题目录，里面每个 <名字>.json 是一个主题
+func ThemesDir() string {
+	return filepath.Join(ConfigDir(), ThemesName)
+}
+
+// SavedPetFile 拖拽图片的保存位置
+func SavedPetFile() string {
+	return filepath.Join(DataDir(), SavedPetName)
+}
+
+// Asset 查找随程序发布的资源文件：优先找可执行文件旁边的 assets 目录，
+// 找不到时 (比如 go run 时可执行文件在临时目录) 再找当前目录下的 assets
+func Asset(name string) string {
+	candidates := []string{}
+	if dir, ok := exeDir(); ok {
+		candidates = append(candidates, filepath.Join(dir, AssetsDir, name))
+	}
+	candidates = append(candidates, filepath.Join(AssetsDir, name))
+
+	for _, p := range candidates {
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return candidates[0]
+}
+
+// exeDir 可执行文件 (解析符号链接之后) 所在的目录
+func exeDir() (string, bool) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", false
+	}
+	if real, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = real
+	}
+	return filepath.Dir(exe), true
+}
+
+// ResolveImage 找到配置里的图片：先按原样 (绝对路径或相对当前目录)，
+// 旧配置里 "assets/xxx" 形式的相对路径再到程序自带的资源目录里找；都找不到返回空
+func ResolveImage(p string) string {
+	if p == "" {
+		return ""
+	}
+	if _, err := os.Stat(p); err == nil {
+		return p
+	}
+	if rest, ok := strings.CutPrefix(filepath.ToSlash(p), AssetsDir+"/"); ok {
+		asset := Asset(rest)
+		if _, err := os.Stat(asset); err == nil {
+			return asset
+		}
+	}
+	return ""
+}
+
+// MigrateLegacy 第一次按新目录运行时，把旧版放在程序目录下的 config.json 与 assets/saved_pet.png
+// 复制到新位置 (原文件保留)；只看可执行文件所在的目录，不会把当前目录里不相干的 config.json 搬过去。
+// 返回 "旧配置里的相对路径 -> 新路径"，调用方可以据此修正配置里引用的旧路径
+func MigrateLegacy() map[string]string {
+	moved := map[string]string{}
+	dir, ok := exeDir()
+	if !ok {
+		return moved
+	}
+	legacy := []struct{ name, to string }{
+		{ConfigName, ConfigFile()},
+		{AssetsDir + "/" + SavedPetName, SavedPetFile()},
+	}
+	for _, f := range legacy {
+		from := filepath.Join(dir, filepath.FromSlash(f.name))
+		if samePath(from, f.to) {
+			continue
+		}
+		if _, err := os.Stat(f.to); err == nil {
+			continue // 新位置已经有了，说明迁移过
+		}
+		if _, err := os.Stat(from); err != nil {
+			continue
+		}
+		if err := copyFile(from, f.to); err != nil {
+			log.Printf("迁移 %s 失败: %v", from, err)
+			continue
+		}
+		log.Printf("已把 %s 迁移到 %s", from, f.to)
+		moved[f.name] = f.to
+	}
+	return moved
+}
+
+// samePath 判断两个路径是否指向同一个位置 (比如 ConfigDir 退回了当前目录)
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
+
+func copyFile(from, to string) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return err
+	}
+	dst, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
@@ -0,0 +1,165 @@
+// Package physics provides the motion of the pet window after it is thrown: inertia, friction, optional
+// gravity and bouncing off the screen edges, integrated with a fixed timestep so the result does not depend on TPS
+package physics
+
+import (
+	"math"
+	"time"
+)
+
+// Step 固定的积分步长：不管 Update 的频率是 8 还是 60，都按每秒 120 步推进
+const Step = time.Second / 120
+
+// maxFrame 单帧最多推进的时间；窗口被拖住或系统卡顿之后不会一下子补算几秒
+const maxFrame = 250 * time.Millisecond
+
+// 默认参数，手感和原来 60 TPS 时每帧乘 0.95、反弹保留 0.6 一致
+const (
+	DefaultFriction    = 3.0  // 每秒的速度衰减系数：速度每秒乘以 e^-friction
+	DefaultRestitution = 0.6  // 撞墙后保留的速度比例
+	DefaultMaxSpeed    = 4000 // 像素/秒
+	DefaultGravity     = 2500 // 打开重力模式时的重力加速度 (像素/秒²)
+)
+
+// minSpeed 低于这个速度 (像素/秒) 就停下，防止微小抖动
+const minSpeed = 5.0
+
+// settleSpeed 重力模式下落地时的竖直速度低于这个值 (像素/秒) 就不再弹起，坐在地面上
+const settleSpeed = 80.0
+
+// Params 可配置的物理参数
+type Params struct {
+	Friction    float64 // 每秒的速度衰减系数，0 表示没有摩擦
+	Restitution float64 // 撞墙后保留的速度比例，0 表示贴墙停住，1 表示完全弹性
+	MaxSpeed    float64 // 速度上限 (像素/秒)，防止甩得太猛飞出屏幕；0 表示不限制
+	Gravity     float64 // 重力加速度 (像素/秒²)，0 表示关闭重力，松手后只按惯性滑行
+	Floor       float64 // 地面离屏幕底边的距离 (像素)，比如留出任务栏的高度
+}
+
+// DefaultParams 默认的物理参数
+func DefaultParams() Params {
+	return Params{Friction: DefaultFriction, Restitution: DefaultRestitution, MaxSpeed: DefaultMaxSpeed}
+}
+
+// Bounds 窗口可以活动的范围 (屏幕大小)
+type Bounds struct {
+	W, H float64
+}
+
+// Body 宠物窗口：位置是左上角，速度单位是像素/秒
+type Body struct {
+	X, Y   float64
+	VX, VY float64
+	W, H   float64
+
+	airborne bool          // 重力模式下还没落稳 (速度为 0 的最高点也要继续下落)
+	acc      time.Duration // 还没积分掉的时间
+}
+
+// Events 一次 Advance 期间发生的事情
+type Events struct {
+	Steps  int     // 推进了多少个固定步长
+	Impact float64 // 撞到地面时最大的下落速度 (像素/秒)，0 表示没有碰到地面
+	Landed bool    // 重力模式下停稳在了地面上
+}
+
+// Moving 是否还在运动 (滑行或下落)
+func (b *Body) Moving() bool {
+	return b.VX != 0 || b.VY != 0 || b.airborne
+}
+
+// Stop 立即停下，并丢掉累积的时间
+func (b *Body) Stop() {
+	b.VX, b.VY = 0, 0
+	b.airborne = false
+	b.acc = 0
+}
+
+// Throw 松手时以 (vx, vy) 的速度甩出去，超过上限时按比例缩小；打开重力时之后会落向地面
+func (b *Body) Throw(vx, vy float64, p Params) {
+	b.VX, b.VY = clampSpeed(vx, vy, p.MaxSpeed)
+	b.airborne = p.Gravity > 0
+	b.acc = 0
+}
+
+// Advance 把真实经过的时间 dt 累积起来，按固定步长推进；
+// 不足一步的余量留到下一帧，所以同样的一次投掷在任何 TPS 下走出的轨迹都一样
+func (b *Body) Advance(dt time.Duration, p Params, bounds Bounds) Events {
+	var ev Events
+	if !b.Moving() {
+		b.acc = 0
+		return ev
+	}
+	if dt > maxFrame {
+		dt = maxFrame
+	}
+	b.acc += dt
+
+	for b.acc >= Step && b.Moving() {
+		b.acc -= Step
+		b.step(Step.Seconds(), p, bounds, &ev)
+		ev.Steps++
+	}
+	return ev
+}
+
+// step 推进一个固定步长
+func (b *Body) step(dt float64, p Params, bounds Bounds, ev *Events) {
+	// 1. 重力与惯性 (飞行中途关掉了重力就当作普通滑行)
+	if p.Gravity <= 0 {
+		b.airborne = false
+	}
+	if b.airborne {
+		b.VY += p.Gravity * dt
+		b.VX, b.VY = clampSpeed(b.VX, b.VY, p.MaxSpeed)
+	}
+	b.X += b.VX * dt
+	b.Y += b.VY * dt
+
+	// 2. 摩擦衰减
+	decay := math.Exp(-p.Friction * dt)
+	b.VX *= decay
+	b.VY *= decay
+
+	// 3. 屏幕边缘碰撞 (反弹)
+	if b.X < 0 {
+		b.X = 0
+		b.VX = -b.VX * p.Restitution
+	}
+	if bounds.W > 0 && b.X+b.W > bounds.W {
+		b.X = bounds.W - b.W
+		b.VX = -b.VX * p.Restitution
+	}
+	if b.Y < 0 {
+		b.Y = 0
+		b.VY = -b.VY * p.Restitution
+	}
+	if floor := bounds.H - p.Floor; bounds.H > 0 && b.Y+b.H > floor {
+		b.Y = floor - b.H
+		if b.VY > ev.Impact {
+			ev.Impact = b.VY
+		}
+		b.VY = -b.VY * p.Restitution
+		// 重力模式下弹不起来了就坐在地面上，之后只剩水平方向的滑行
+		if b.airborne && math.Abs(b.VY) < settleSpeed {
+			b.VY = 0
+			b.airborne = false
+			ev.Landed = true
+		}
+	}
+
+	// 4. 速度过低直接归零
+	if !b.airborne && math.Hypot(b.VX, b.VY) < minSpeed {
+		b.VX, b.VY = 0, 0
+	}
+}
+
+// clampSpeed 把速度限制在 max 以内，方向不变；max <= 0 表示不限制
+func clampSpeed(vx, vy, max float64) (float64, float64) {
+	speed := math.Hypot(vx, vy)
+	if max <= 0 || speed <= max {
+		return vx, vy
+	}
+	k := max / speed
+	return vx * k, vy * k
+}
@@ -0,0 +1,234 @@
+package physics
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// throwAt 以 tps 的帧率推进同一次投掷，直到停下 (最多模拟 30 秒)
+func throwAt(t *testing.T, tps int, p Params) Body {
+	t.Helper()
+	b := Body{X: 300, Y: 200, W: 100, H: 100}
+	b.Throw(1500, -800, p)
+
+	bounds := Bounds{W: 1280, H: 720}
+	dt := time.Second / time.Duration(tps)
+	for elapsed := time.Duration(0); b.Moving(); elapsed += dt {
+		if elapsed > 30*time.Second {
+			t.Fatalf("%d TPS: still moving after 30s: %+v", tps, b)
+		}
+		b.Advance(dt, p, bounds)
+	}
+	return b
+}
+
+func TestThrowSameAtAnyTPS(t *testing.T) {
+	gravity := DefaultParams()
+	gravity.Gravity = DefaultGravity
+
+	tests := []struct {
+		name string
+		p    Params
+	}{
+		{"slide", DefaultParams()},
+		{"gravity", gravity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := throwAt(t, 60, tt.p)
+			for _, tps := range []int{8, 30, 144} {
+				got := throwAt(t, tps, tt.p)
+				if got.X != want.X || got.Y != want.Y || got.VX != want.VX || got.VY != want.VY {
+					t.Errorf("%d TPS rests at (%v, %v) v=(%v, %v), 60 TPS at (%v, %v) v=(%v, %v)",
+						tps, got.X, got.Y, got.VX, got.VY, want.X, want.Y, want.VX, want.VY)
+				}
+			}
+			if tt.p.Gravity > 0 && want.Y != 720-want.H {
+				t.Errorf("rests at y = %v, want on the floor at %v", want.Y, 720-want.H)
+			}
+		})
+	}
+}
+
+func TestThrowMaxSpeed(t *testing.T) {
+	tests := []struct {
+		max  float64
+		want float64
+	}{
+		{DefaultMaxSpeed, DefaultMaxSpeed},
+		{0, 10000}, // 0 表示不限制
+	}
+	for _, tt := range tests {
+		var b Body
+		b.Throw(6000, 8000, Params{MaxSpeed: tt.max})
+		if got := math.Hypot(b.VX, b.VY); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("max %v: speed = %v, want %v", tt.max, got, tt.want)
+		}
+	}
+}
+
+// drop 打开重力后从 (x, y) 松手，每帧 1/60 秒推进到停稳，返回每次撞地的速度和 Landed 出现的次数
+func drop(t *testing.T, b *Body, p Params, bounds Bounds) (impacts []float64, landed int) {
+	t.Helper()
+	b.Throw(0, 0, p)
+	for elapsed := time.Duration(0); b.Moving(); elapsed += time.Second / 60 {
+		if elapsed > 30*time.Second {
+			t.Fatalf("still moving after 30s: %+v", *b)
+		}
+		ev := b.Advance(time.Second/60, p, bounds)
+		if ev.Impact > 0 {
+			impacts = append(impacts, ev.Impact)
+		}
+		if ev.Landed {
+			landed++
+		}
+	}
+	return impacts, landed
+}
+
+func TestGravityLanding(t *testing.T) {
+	bounds := Bounds{W: 1280, H: 720}
+	tests := []struct {
+		name  string
+		floor float64
+	}{
+		{"screen bottom", 0},
+		{"above taskbar", 48},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := DefaultParams()
+			p.Gravity = DefaultGravity
+			p.Floor = tt.floor
+			b := Body{X: 300, Y: 0, W: 100, H: 100}
+
+			impacts, landed := drop(t, &b, p, bounds)
+			if want := bounds.H - tt.floor - b.H; b.Y != want {
+				t.Errorf("rests at y = %v, want %v", b.Y, want)
+			}
+			if b.VX != 0 || b.VY != 0 {
+				t.Errorf("velocity at rest = (%v, %v), want 0", b.VX, b.VY)
+			}
+			if landed != 1 {
+				t.Errorf("Landed reported %d times, want once", landed)
+			}
+			// 每次弹起都比上一次低，最后才坐稳
+			if len(impacts) < 2 {
+				t.Fatalf("impacts = %v, want a few bounces before settling", impacts)
+			}
+			for i := 1; i < len(impacts); i++ {
+				if impacts[i] >= impacts[i-1] {
+					t.Errorf("impact %d = %v, not below the previous %v", i, impacts[i], impacts[i-1])
+				}
+			}
+		})
+	}
+}
+
+func TestRestitution(t *testing.T) {
+	bounds := Bounds{W: 1280, H: 720}
+	for _, r := range []float64{0, 0.3, 0.6, 1} {
+		p := Params{Restitution: r, Gravity: DefaultGravity}
+		// 离地面只差一点、正在往下掉，下一步就会撞到地面
+		b := Body{X: 300, Y: 720 - 100 - 1, W: 100, H: 100}
+		b.Throw(0, 1000, p)
+
+		ev := b.Advance(Step, p, bounds)
+		if ev.Impact == 0 {
+			t.Fatalf("restitution %v: no impact after one step", r)
+		}
+		want := -ev.Impact * r
+		if -want < settleSpeed {
+			want = 0 // 弹不起来直接坐稳
+		}
+		if math.Abs(b.VY-want) > 1e-9 {
+			t.Errorf("restitution %v: vy after bounce = %v, want %v", r, b.VY, want)
+		}
+		if ev.Landed != (want == 0) {
+			t.Errorf("restitution %v: Landed = %v", r, ev.Landed)
+		}
+	}
+}
+
+func TestSlideComesToRest(t *testing.T) {
+	p := DefaultParams()
+	b := Body{X: 300, Y: 200, W: 100, H: 100}
+	b.Throw(400, 0, p)
+
+	last := b.X
+	for elapsed := time.Duration(0); b.Moving(); elapsed += time.Second / 60 {
+		if elapsed > 30*time.Second {
+			t.Fatalf("still moving after 30s: %+v", b)
+		}
+		b.Advance(time.Second/60, p, Bounds{W: 1280, H: 720})
+		if b.X < last {
+			t.Fatalf("slid backwards from %v to %v without hitting a wall", last, b.X)
+		}
+		last = b.X
+	}
+	if b.Y != 200 {
+		t.Errorf("y = %v, want 200 without gravity", b.Y)
+	}
+	if b.Advance(time.Second, p, Bounds{W: 1280, H: 720}).Steps != 0 {
+		t.Error("a body at rest kept stepping")
+	}
+}
+
+// drag 以 60 帧/秒记录一段拖拽，pos(i) 给出第 i 帧的位置
+func drag(tr *Tracker, frames int, pos func(i int) (float64, float64)) {
+	for i := 0; i < frames; i++ {
+		x, y := pos(i)
+		tr.Add(x, y, time.Second/60)
+	}
+}
+
+func TestTrackerNoisy(t *testing.T) {
+	// 以 (1200, -600) 像素/秒匀速拖动，每帧叠加 ±4 像素的抖动 (鼠标采样误差)
+	var tr Tracker
+	drag(&tr, 30, func(i int) (float64, float64) {
+		noise := 4.0
+		if i%2 == 1 {
+			noise = -4
+		}
+		s := float64(i) / 60
+		return 1200*s + noise, -600*s - noise
+	})
+
+	vx, vy := tr.Velocity()
+	if math.Abs(vx-1200) > 1200*0.15 || math.Abs(vy+600) > 600*0.3 {
+		t.Errorf("velocity = (%v, %v), want about (1200, -600)", vx, vy)
+	}
+
+	// 只看最后一帧的位移会被抖动带偏很多，拟合的结果要比它准
+	lastVX := (1200.0/60 - 8) * 60
+	if math.Abs(vx-1200) >= math.Abs(lastVX-1200) {
+		t.Errorf("fit vx = %v is no better than the last frame's %v", vx, lastVX)
+	}
+}
+
+func TestTrackerDropsStaleSamples(t *testing.T) {
+	var tr Tracker
+
+	// 先快速拖动，然后停住超过 ThrowWindow：松手时不应该被甩出去
+	drag(&tr, 20, func(i int) (float64, float64) { return float64(i) * 30, 0 })
+	drag(&tr, 10, func(int) (float64, float64) { return 19 * 30, 0 })
+	if vx, vy := tr.Velocity(); vx != 0 || vy != 0 {
+		t.Errorf("velocity after holding still = (%v, %v), want 0", vx, vy)
+	}
+
+	// 快速拖动之后慢下来：只按最近 ThrowWindow 内的慢速估计
+	tr.Reset()
+	drag(&tr, 20, func(i int) (float64, float64) { return float64(i) * 30, 0 })
+	drag(&tr, 10, func(i int) (float64, float64) { return 19*30 + float64(i+1), 0 })
+	if vx, _ := tr.Velocity(); math.Abs(vx-60) > 1e-3 {
+		t.Errorf("velocity after slowing down = %v, want 60 from the recent samples only", vx)
+	}
+
+	// 只有一个采样时无法估计
+	tr.Reset()
+	tr.Add(10, 10, time.Second/60)
+	if vx, vy := tr.Velocity(); vx != 0 || vy != 0 {
+		t.Errorf("velocity from one sample = (%v, %v), want 0", vx, vy)
+	}
+}
@@ -0,0 +1,73 @@
+package physics
+
+import "time"
+
+// ThrowWindow 估计甩出速度时只看松手前这么长时间内的拖拽轨迹
+const ThrowWindow = 100 * time.Millisecond
+
+// maxSamples 最多保留的拖拽采样数
+const maxSamples = 32
+
+type sample struct {
+	t    time.Duration // 从开始拖拽算起的时间
+	x, y float64
+}
+
+// Tracker 记录最近一段拖拽轨迹，松手时用它估计甩出去的速度；
+// 对最近 ThrowWindow 内的采样做最小二乘拟合，比只看最后一帧的位移稳定得多
+type Tracker struct {
+	samples []sample
+	now     time.Duration
+}
+
+// Reset 开始新的一次拖拽
+func (t *Tracker) Reset() {
+	t.samples = t.samples[:0]
+	t.now = 0
+}
+
+// Add 记录一个采样：距离上一次采样过了 dt，窗口在 (x, y)
+func (t *Tracker) Add(x, y float64, dt time.Duration) {
+	t.now += dt
+	t.samples = append(t.samples, sample{t: t.now, x: x, y: y})
+	if len(t.samples) > maxSamples {
+		t.samples = append(t.samples[:0], t.samples[len(t.samples)-maxSamples:]...)
+	}
+}
+
+// Velocity 估计当前的速度 (像素/秒)；松手前停住不动的话结果接近 0
+func (t *Tracker) Velocity() (vx, vy float64) {
+	// 1. 取最近 ThrowWindow 内的采样
+	var recent []sample
+	for i := len(t.samples) - 1; i >= 0; i-- {
+		if t.now-t.samples[i].t > ThrowWindow {
+			break
+		}
+		recent = append(recent, t.samples[i])
+	}
+	if len(recent) < 2 {
+		return 0, 0
+	}
+
+	// 2. 位置对时间做线性拟合，斜率就是速度
+	var mt, mx, my float64
+	for _, s := range recent {
+		mt += s.t.Seconds()
+		mx += s.x
+		my += s.y
+	}
+	n := float64(len(recent))
+	mt, mx, my = mt/n, mx/n, my/n
+
+	var stt, stx, sty float64
+	for _, s := range recent {
+		dt := s.t.Seconds() - mt
+		stt += dt * dt
+		stx += dt * (s.x - mx)
+		sty += dt * (s.y - my)
+	}
+	if stt == 0 {
+		return 0, 0
+	}
+	return stx / stt, sty / stt
+}
@@ -0,0 +1,118 @@
+// Package remote provides a headless metrics agent and a pet-side source that consumes it
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"0xPet/internal/monitor"
+)
+
+// 对外暴露的路径
+const (
+	PathSample = "/sample" // GET：最新一次采样 (JSON)
+	PathEvents = "/events" // GET：按采样间隔推送的 SSE 流
+)
+
+// Agent 无界面的采样服务：在被监控的机器上运行，把 monitor 的数据通过 HTTP 发出去
+type Agent struct {
+	addr     string
+	interval time.Duration
+	metrics  func() monitor.Metrics
+	srv      *http.Server
+	ln       net.Listener
+	cancel   context.CancelFunc // 取消所有请求的上下文，让 SSE 连接在 Close 时结束
+}
+
+// NewAgent 创建 agent；metrics 一般传 monitor.GetMetrics
+func NewAgent(addr string, interval time.Duration, metrics func() monitor.Metrics) *Agent {
+	return &Agent{addr: addr, interval: interval, metrics: metrics}
+}
+
+// Start 绑定地址并在后台开始服务
+func (a *Agent) Start() error {
+	ln, err := net.Listen("tcp", a.addr)
+	if err != nil {
+		return err
+	}
+	a.ln = ln
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(PathSample, a.handleSample)
+	mux.HandleFunc(PathEvents, a.handleEvents)
+	ctx, cancel := context.WithCancel(context.Background())
+	a.cancel = cancel
+	a.srv = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		if err := a.srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			log.Println("agent 异常退出:", err)
+		}
+	}()
+	return nil
+}
+
+// Addr 实际监听的地址 (addr 里端口写 0 时由系统分配)
+func (a *Agent) Addr() string {
+	if a.ln == nil {
+		return a.addr
+	}
+	return a.ln.Addr().String()
+}
+
+// Close 关闭服务，正在进行的 SSE 连接会被断开
+func (a *Agent) Close() error {
+	if a.srv == nil {
+		return nil
+	}
+	a.cancel()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return a.srv.Shutdown(ctx)
+}
+
+func (a *Agent) handleSample(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(a.metrics()); err != nil {
+		log.Println("agent 写出采样失败:", err)
+	}
+}
+
+// handleEvents 每个采样间隔推送一条 "data: <json>" 事件，直到客户端断开
+func (a *Agent) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+	for {
+		data, err := json.Marshal(a.metrics())
+		if err != nil {
+			return
+		}
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
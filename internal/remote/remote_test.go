@@ -0,0 +1,195 @@
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"0xPet/internal/monitor"
+)
+
+// fakeMetrics agent 端的假数据，测试中途可以替换
+type fakeMetrics struct {
+	mu sync.Mutex
+	m  monitor.Metrics
+}
+
+func (f *fakeMetrics) get() monitor.Metrics {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.m
+}
+
+func (f *fakeMetrics) set(m monitor.Metrics) {
+	f.mu.Lock()
+	f.m = m
+	f.mu.Unlock()
+}
+
+// startAgent 在本机随机端口启动 agent，测试结束时关闭
+func startAgent(t *testing.T, f *fakeMetrics) string {
+	t.Helper()
+	a := NewAgent("127.0.0.1:0", 10*time.Millisecond, f.get)
+	if err := a.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { a.Close() })
+	return "http://" + a.Addr()
+}
+
+// skewed agent 的时钟比本机慢一个小时
+var skewed = time.Now().Add(-time.Hour).Truncate(time.Second)
+
+func TestJSONSource(t *testing.T) {
+	f := &fakeMetrics{m: monitor.Metrics{Time: skewed, Host: "buildbox", CPU: 42}}
+	src, err := NewSource(startAgent(t, f), ModeJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := time.Now()
+	m, err := src.Sample()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.CPU != 42 || src.Name() != "buildbox" {
+		t.Errorf("got cpu %v from %q, want 42 from buildbox", m.CPU, src.Name())
+	}
+	if m.Time.Before(before) || m.Time.After(time.Now()) {
+		t.Errorf("sample time = %v, want local receive time", m.Time)
+	}
+
+	// agent 还没有新的采样：同一条不能被记录两次
+	if _, err := src.Sample(); !errors.Is(err, ErrNoData) || !errors.Is(err, monitor.ErrNoNewSample) {
+		t.Errorf("repeated sample error = %v, want ErrNoData", err)
+	}
+
+	f.set(monitor.Metrics{Time: skewed.Add(monitor.Interval), Host: "buildbox", CPU: 7})
+	if m, err := src.Sample(); err != nil || m.CPU != 7 {
+		t.Errorf("next sample = %v, %v; want cpu 7", m.CPU, err)
+	}
+}
+
+func TestSSESource(t *testing.T) {
+	f := &fakeMetrics{m: monitor.Metrics{Time: skewed, Host: "buildbox", CPU: 55}}
+	src, err := NewSource(startAgent(t, f), ModeSSE)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 订阅在后台建立，等第一条推送
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		m, err := src.Sample()
+		if err == nil {
+			if m.CPU != 55 || time.Since(m.Time) > time.Minute {
+				t.Errorf("got cpu %v at %v, want 55 stamped with local time", m.CPU, m.Time)
+			}
+			break
+		}
+		if !errors.Is(err, ErrNoData) {
+			t.Fatal(err)
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no event from agent")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// agent 每 10ms 推送一次同一条采样，都应该被当作重复丢掉
+	time.Sleep(50 * time.Millisecond)
+	if _, err := src.Sample(); !errors.Is(err, ErrNoData) {
+		t.Errorf("repeated push error = %v, want ErrNoData", err)
+	}
+}
+
+func TestNewSourceErrors(t *testing.T) {
+	for _, tt := range []struct{ base, mode string }{
+		{"buildbox", ModeJSON},
+		{"http://buildbox:9465", "websocket"},
+	} {
+		if _, err := NewSource(tt.base, tt.mode); err == nil {
+			t.Errorf("NewSource(%q, %q) succeeded, want error", tt.base, tt.mode)
+		}
+	}
+}
+
+// hangingAgent 只推送一条事件然后一直不说话的 agent；连接被客户端断开时往 dropped 发信号
+func hangingAgent(t *testing.T) (base string, dropped <-chan struct{}) {
+	t.Helper()
+	ch := make(chan struct{}, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "data: {\"host\":\"quiet\",\"cpu\":9,\"time\":%q}\n\n", skewed.Format(time.RFC3339))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+		ch <- struct{}{}
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, ch
+}
+
+func TestSSEIdleTimeout(t *testing.T) {
+	base, dropped := hangingAgent(t)
+	src, err := newSource(base, ModeSSE, 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	select {
+	case <-dropped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("idle SSE connection was not dropped")
+	}
+	if m, err := src.Sample(); err != nil || m.CPU != 9 {
+		t.Errorf("Sample = %v, %v; want the one pushed event", m.CPU, err)
+	}
+}
+
+func TestSourceClose(t *testing.T) {
+	base, dropped := hangingAgent(t)
+	src, err := newSource(base, ModeSSE, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 等第一条推送，确认连接已经建立
+	deadline := time.Now().Add(5 * time.Second)
+	for _, err := src.Sample(); err != nil; _, err = src.Sample() {
+		if time.Now().After(deadline) {
+			t.Fatal("no event from agent")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		src.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not return after the subscriber exited")
+	}
+	select {
+	case <-dropped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection still open after Close")
+	}
+
+	// JSON 模式 Close 之后请求直接失败，重复 Close 也没问题
+	f := &fakeMetrics{m: monitor.Metrics{Time: skewed, CPU: 1}}
+	js, err := NewSource(startAgent(t, f), ModeJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	js.Close()
+	js.Close()
+	if _, err := js.Sample(); err == nil {
+		t.Error("Sample after Close succeeded")
+	}
+}
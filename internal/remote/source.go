@@ -0,0 +1,216 @@
+package remote
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"0xPet/internal/monitor"
+)
+
+// 拉取模式
+const (
+	ModeJSON = "json" // 每次 Sample 发一次 GET /sample
+	ModeSSE  = "sse"  // 长连接订阅 /events，Sample 取最近收到的一条
+)
+
+// IdleTimeout SSE 连接超过这么久没有收到任何数据就断开重连 (agent 每个采样间隔至少推送一条)
+const IdleTimeout = monitor.StaleAfter
+
+// ErrNoData 自上次 Sample 以来 agent 没有产生新的采样 (SSE 没有推送，或 JSON 轮询到的还是同一条)
+var ErrNoData = fmt.Errorf("agent 没有新数据: %w", monitor.ErrNoNewSample)
+
+// Source 消费远程 agent 的指标来源，实现 monitor.Source
+type Source struct {
+	base   string
+	mode   string
+	client *http.Client
+	idle   time.Duration // SSE 连接的空闲超时
+
+	ctx    context.Context // Close 时取消，结束正在进行的请求与订阅协程
+	cancel context.CancelFunc
+	done   chan struct{} // 订阅协程退出时关闭，JSON 模式下为 nil
+
+	mu        sync.Mutex
+	host      string
+	latest    monitor.Metrics
+	fresh     bool      // latest 是否还没被 Sample 取走
+	agentTime time.Time // agent 报告的上一条采样时间，用来跳过重复收到的同一条
+}
+
+// NewSource 创建远程来源；base 形如 "http://buildbox:9465"，mode 为 ModeJSON 或 ModeSSE
+func NewSource(base, mode string) (*Source, error) {
+	return newSource(base, mode, IdleTimeout)
+}
+
+func newSource(base, mode string, idle time.Duration) (*Source, error) {
+	u, err := url.Parse(base)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("无效的 agent 地址: %q", base)
+	}
+	if mode == "" {
+		mode = ModeJSON
+	}
+	if mode != ModeJSON && mode != ModeSSE {
+		return nil, fmt.Errorf("未知的拉取模式: %q", mode)
+	}
+
+	s := &Source{
+		base: strings.TrimRight(base, "/"),
+		mode: mode,
+		host: u.Hostname(),
+		idle: idle,
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	if mode == ModeJSON {
+		s.client = &http.Client{Timeout: monitor.Interval}
+	} else {
+		// SSE 是长连接，不能设整体超时，靠 stream 里的空闲超时发现失联
+		s.client = &http.Client{}
+		s.done = make(chan struct{})
+		go s.subscribe()
+	}
+	return s, nil
+}
+
+// Close 断开与 agent 的连接并等订阅协程退出；之后的 Sample 都会返回错误
+func (s *Source) Close() error {
+	s.cancel()
+	if s.done != nil {
+		<-s.done
+	}
+	return nil
+}
+
+// Name 被监控机器的主机名 (收到第一条数据前用 URL 里的主机名)
+func (s *Source) Name() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.host
+}
+
+// Sample 实现 monitor.Source
+func (s *Source) Sample() (monitor.Metrics, error) {
+	if s.mode == ModeSSE {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if !s.fresh {
+			return monitor.Metrics{}, ErrNoData
+		}
+		s.fresh = false
+		return s.latest, nil
+	}
+
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodGet, s.base+PathSample, nil)
+	if err != nil {
+		return monitor.Metrics{}, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return monitor.Metrics{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return monitor.Metrics{}, fmt.Errorf("agent 返回 %s", resp.Status)
+	}
+
+	var m monitor.Metrics
+	if err := json.NewDecoder(resp.Body).Decode(&m); err != nil {
+		return monitor.Metrics{}, err
+	}
+	if !s.store(m) {
+		return monitor.Metrics{}, ErrNoData
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fresh = false
+	return s.latest, nil
+}
+
+// store 记录最新数据，并用 agent 报告的主机名作为显示名；
+// 时间戳换成本机收到的时间，两台机器的时钟有偏差时规则的时间窗口也不会错位。
+// agent 时间戳没变 (同一条采样被取了两次) 时返回 false
+func (s *Source) store(m monitor.Metrics) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !m.Time.IsZero() && m.Time.Equal(s.agentTime) {
+		return false
+	}
+	s.agentTime = m.Time
+	m.Time = time.Now()
+	s.latest = m
+	s.fresh = true
+	if m.Host != "" {
+		s.host = m.Host
+	}
+	return true
+}
+
+// subscribe 保持 SSE 连接，断线后按指数退避重连 (最长 30 秒)，直到 Close
+func (s *Source) subscribe() {
+	defer close(s.done)
+	backoff := time.Second
+	for {
+		err := s.stream()
+		if s.ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			backoff = time.Second
+		}
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+// stream 读取一条 SSE 连接直到断开；连接建立或两次收到数据之间超过 idle 就主动断开
+func (s *Source) stream() error {
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+	idle := time.AfterFunc(s.idle, cancel)
+	defer idle.Stop()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.base+PathEvents, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("agent 返回 %s", resp.Status)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		idle.Reset(s.idle)
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		var m monitor.Metrics
+		if err := json.Unmarshal([]byte(strings.TrimSpace(line[len("data:"):])), &m); err != nil {
+			continue
+		}
+		s.store(m)
+	}
+	if ctx.Err() != nil && s.ctx.Err() == nil {
+		return fmt.Errorf("agent 超过 %v 没有推送数据", s.idle)
+	}
+	return scanner.Err()
+}
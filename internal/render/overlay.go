@@ -0,0 +1,109 @@
+package render
+
+import (
+	"image/color"
+
+	"0xPet/internal/hud"
+	"0xPet/internal/monitor"
+	"0xPet/internal/theme"
+
+	"golang.org/x/image/font"
+)
+
+// LineHeight 小字体的行高，HUD、气泡与各种面板都按它排版
+const LineHeight = 9
+
+// TextLines 逐行画文字，第一行的基线在 (x, y)，之后每行往下 LineHeight
+func TextLines(c Canvas, face font.Face, lines []string, x, y int, clr color.Color) {
+	for i, line := range lines {
+		c.Text(line, face, x, y+i*LineHeight, clr)
+	}
+}
+
+// BoxStyle 文字框的配色
+type BoxStyle struct {
+	Bg     color.Color
+	Border color.Color // 非 nil 时在顶部画一条 1px 的边线
+	Text   color.Color
+}
+
+// BoxHeight n 行文字的文字框高度 (上下各留 3px)
+func BoxHeight(n int) int {
+	return n*LineHeight + 6
+}
+
+// Box 画一个左上角在 (x, y)、宽 w 的文字框：底色、可选的顶部边线，再用小字体逐行写上文字
+func Box(c Canvas, fonts Fonts, lines []string, x, y, w int, st BoxStyle) {
+	c.FillRect(float32(x), float32(y), float32(w), float32(BoxHeight(len(lines))), st.Bg)
+	if st.Border != nil {
+		c.FillRect(float32(x), float32(y), float32(w), 1, st.Border)
+	}
+	TextLines(c, fonts.Small, lines, x+4, y+LineHeight+2, st.Text)
+}
+
+// PanelCols 宽 petW 的宠物上能放下的小字列数 (每个字符 4px)，至少 min 列
+func PanelCols(petW, min int) int {
+	if cols := petW / 4; cols > min {
+		return cols
+	}
+	return min
+}
+
+// bottomBox 把文字框贴在宠物区域 (左上角 (0, petY)，尺寸 petW x petH) 的底部，放不下时顶到宠物顶部
+func bottomBox(c Canvas, fonts Fonts, lines []string, petY, petH, cols int, st BoxStyle) {
+	y := petY + petH - BoxHeight(len(lines))
+	if y < petY {
+		y = petY
+	}
+	Box(c, fonts, lines, 0, y, cols*4, st)
+}
+
+// Bubble 在宠物底部画一个气泡，文字按宽度折行，最多 4 行
+func Bubble(c Canvas, fonts Fonts, text string, petY, petW, petH int, th *theme.Theme) {
+	cols := PanelCols(petW, 16)
+	lines := hud.Wrap(text, cols-2, 4)
+	bottomBox(c, fonts, lines, petY, petH, cols, BoxStyle{
+		Bg:     th.BubbleBg.RGBA(),
+		Border: th.BubbleBorder.RGBA(),
+		Text:   th.Bubble.RGBA(),
+	})
+}
+
+// Job 在宠物底部显示正在运行的命令 (第一行) 和它最新的一行输出 (可选)，每行超出宽度的部分截掉
+func Job(c Canvas, fonts Fonts, lines []string, petY, petW, petH int, th *theme.Theme) {
+	cols := PanelCols(petW, 16)
+	out := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if line != "" {
+			out = append(out, hud.Wrap(line, cols-2, 1)[0])
+		}
+	}
+	if len(out) == 0 {
+		return
+	}
+	bottomBox(c, fonts, out, petY, petH, cols, BoxStyle{Bg: th.PanelBg.RGBA(), Text: th.Accent.RGBA()})
+}
+
+// ProcPanel 在宠物顶部叠加"谁在吃资源"的面板：平时只显示榜首，expanded 时展开完整排行
+func ProcPanel(c Canvas, fonts Fonts, top monitor.TopProcs, expanded bool, petY, petW int, th *theme.Theme) {
+	if len(top.ByCPU) == 0 {
+		return
+	}
+	cols := PanelCols(petW, 20)
+
+	var lines []string
+	if expanded {
+		lines = append(lines, "TOP CPU")
+		for _, p := range top.ByCPU {
+			lines = append(lines, hud.ProcLine(p.PID, p.Name, p.CPU, cols))
+		}
+		lines = append(lines, "TOP MEM")
+		for _, p := range top.ByMem {
+			lines = append(lines, hud.ProcLine(p.PID, p.Name, p.Mem, cols))
+		}
+	} else {
+		p := top.ByCPU[0]
+		lines = append(lines, hud.ProcLine(p.PID, p.Name, p.CPU, cols))
+	}
+	Box(c, fonts, lines, 0, petY, cols*4+4, BoxStyle{Bg: th.PanelBg.RGBA(), Text: th.Panel.RGBA()})
+}
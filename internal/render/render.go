@@ -0,0 +1,218 @@
+// Package render provides the drawing routines for the pet, the HUD and the menu on top of a small
+// Canvas interface, so the same code draws into the ebiten window and into a plain image.RGBA
+package render
+
+import (
+	"fmt"
+	"image/color"
+	"os"
+
+	"0xPet/internal/entity"
+	"0xPet/internal/theme"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+)
+
+// Canvas 绘图目标：窗口里是 ebiten 的图像，截图和无界面时是 image.RGBA
+type Canvas interface {
+	// Text 画一段文字，(x, y) 是第一个字符的基线起点
+	Text(s string, face font.Face, x, y int, c color.Color)
+	// FillRect 画一个实心矩形，按 alpha 与底下的内容混合
+	FillRect(x, y, w, h float32, c color.Color)
+}
+
+// 窗口布局：宠物上方留 HUDHeight 给 HUD，右侧是 MenuWidth 宽的菜单
+const (
+	HUDHeight     = 30
+	MenuWidth     = 160 // 宽度收紧，足够放下13px的纯文本
+	MenuRowHeight = 25  // 每行的高度，无额外 Gap
+	MenuStartY    = 20  // 顶部留白
+	MinMenuH      = 180 // 菜单的最小安全高度 (20 + 6*25 + 10底部留白)
+)
+
+// Fonts 一大一小两个字库实例：正常模式用大字，高清与迷你模式、HUD 用小字
+type Fonts struct {
+	Normal font.Face
+	Small  font.Face
+}
+
+// LoadFonts 从 TTF 文件生成 16px 与 8px 两个字库实例
+func LoadFonts(path string) (Fonts, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Fonts{}, fmt.Errorf("无法加载字体文件: %w", err)
+	}
+	tt, err := opentype.Parse(data)
+	if err != nil {
+		return Fonts{}, fmt.Errorf("解析字体失败: %w", err)
+	}
+	var f Fonts
+	if f.Normal, err = opentype.NewFace(tt, &opentype.FaceOptions{Size: 16, DPI: 72}); err != nil {
+		return Fonts{}, fmt.Errorf("生成字体失败: %w", err)
+	}
+	if f.Small, err = opentype.NewFace(tt, &opentype.FaceOptions{Size: 8, DPI: 72}); err != nil {
+		return Fonts{}, fmt.Errorf("生成字体失败: %w", err)
+	}
+	return f, nil
+}
+
+// Cells 显示模式对应的字符画列数与每个字符格子的像素尺寸 (0=正常 1=高清 2=迷你)
+func Cells(mode int) (cols, cellW, cellH int) {
+	switch mode {
+	case 1: // 高清模式
+		return 100, 4, 8
+	case 2: // 迷你模式
+		return 50, 4, 8
+	default: // 正常模式
+		return 50, 8, 16
+	}
+}
+
+// Face 显示模式对应的字体
+func (f Fonts) Face(mode int) font.Face {
+	if mode == 0 {
+		return f.Normal
+	}
+	return f.Small
+}
+
+// PetSize 字符画在某个显示模式下的像素尺寸 (含顶部 20px 留白)
+func PetSize(grid [][]entity.CharData, mode int) (w, h int) {
+	_, cellW, cellH := Cells(mode)
+	cols := 0
+	for _, row := range grid {
+		if len(row) > cols {
+			cols = len(row)
+		}
+	}
+	return cols * cellW, len(grid)*cellH + 20
+}
+
+// PetStyle 宠物字符的着色方式
+type PetStyle struct {
+	Mode      int
+	Theme     *theme.Theme
+	ShowColor bool        // 用原图颜色 (按主题提亮)，否则用主题的单色
+	Stressed  bool        // 高压时统一用主题的 stress 色
+	Tint      color.Color // 非 nil 时优先于以上所有设置 (反应的颜色)
+}
+
+// Pet 把字符画逐字画到 c 上，(0, 0) 是第一行的基线起点
+func Pet(c Canvas, fonts Fonts, grid [][]entity.CharData, st PetStyle) {
+	th := st.Theme
+	face := fonts.Face(st.Mode)
+	_, cellW, cellH := Cells(st.Mode)
+
+	for r, row := range grid {
+		for col, charData := range row {
+			x := col * cellW
+			y := r * cellH
+
+			var drawColor color.Color
+			if st.Tint != nil {
+				drawColor = st.Tint
+			} else if st.Stressed {
+				drawColor = th.Stress.RGBA()
+			} else if st.ShowColor {
+				rc, gc, bc, ac := charData.Color.RGBA()
+				boost := func(v uint32) uint8 {
+					val := float64(v>>8) * th.ColorBoost
+					if val > 255 {
+						return 255
+					}
+					return uint8(val)
+				}
+				drawColor = color.RGBA{boost(rc), boost(gc), boost(bc), uint8(ac >> 8)}
+			} else {
+				drawColor = th.Mono.RGBA()
+			}
+
+			r32, g32, b32, _ := drawColor.RGBA()
+			r8, g8, b8 := r32>>8, g32>>8, b32>>8
+			luminance := (r8*299 + g8*587 + b8*114) / 1000
+
+			if luminance > 70 && charData.Char != " " && th.ShadowAlpha > 0 {
+				shadowColor := color.RGBA{0, 0, 0, th.ShadowAlpha}
+				c.Text(charData.Char, face, x+1, y+1, shadowColor)
+			}
+			c.Text(charData.Char, face, x, y, drawColor)
+		}
+	}
+}
+
+// HUD 把 HUD 文本行画在顶部 HUDHeight 的留白里；放不下的行压在宠物上，垫一层底色保证可读
+func HUD(c Canvas, fonts Fonts, lines []string, width int, th *theme.Theme) {
+	if extra := len(lines) - 3; extra > 0 {
+		c.FillRect(0, HUDHeight, float32(width), float32(extra*LineHeight+3), th.HUDBg.RGBA())
+	}
+	TextLines(c, fonts.Small, lines, 0, LineHeight, th.HUD.RGBA())
+}
+
+// MenuState 菜单上显示的各项设置
+type MenuState struct {
+	ShowColor   bool
+	ShowMonitor bool
+	Mode        int
+	Profile     string // 已经截断好的显示名
+	Theme       string
+}
+
+// MenuItems 菜单的行数，点击判定与绘制共用
+const MenuItems = 6
+
+// Menu 画完整的右键菜单 (MenuWidth x height)，左上角在 (0, 0)
+func Menu(c Canvas, fonts Fonts, st MenuState, height int, th *theme.Theme) {
+	c.FillRect(0, 0, float32(MenuWidth), float32(height), th.MenuBg.RGBA())
+	c.FillRect(0, 0, 2, float32(height), th.Accent.RGBA())
+
+	type menuItem struct {
+		label string
+		state bool
+	}
+	items := []menuItem{
+		{"COLOR", st.ShowColor},
+		{"HUD", st.ShowMonitor},
+		{"MODE", false},
+		{"PROFILE", false},
+		{"THEME", false},
+		{"EXIT", false},
+	}
+
+	baseTextX := 15
+	menuFont := fonts.Normal
+
+	for i, item := range items {
+		textY := MenuStartY + i*MenuRowHeight + 18
+		var symbol string
+		var drawCol color.Color = th.MenuText.RGBA()
+
+		if item.label == "MODE" {
+			modes := []string{"NORMAL", "HI-RES", "MINI"}
+			item.label = item.label + ": " + modes[st.Mode]
+			symbol = "[~]"
+			drawCol = th.MenuValue.RGBA()
+		} else if item.label == "PROFILE" {
+			item.label = "PROF: " + st.Profile
+			symbol = "[@]"
+			drawCol = th.MenuValue.RGBA()
+		} else if item.label == "THEME" {
+			item.label = "THEME: " + st.Theme
+			symbol = "[#]"
+			drawCol = th.MenuValue.RGBA()
+		} else if item.label == "EXIT" {
+			symbol = "[!]"
+			drawCol = th.MenuDanger.RGBA() // 警示色
+		} else {
+			if item.state {
+				symbol = "[*]"
+				drawCol = th.Accent.RGBA() // 高亮色
+			} else {
+				symbol = "[ ]"
+			}
+		}
+
+		fullText := fmt.Sprintf("%s %s", symbol, item.label)
+		c.Text(fullText, menuFont, baseTextX, textY, drawCol)
+	}
+}
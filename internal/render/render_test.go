@@ -0,0 +1,163 @@
+package render
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"0xPet/internal/entity"
+	"0xPet/internal/monitor"
+	"0xPet/internal/theme"
+)
+
+// 改了绘制逻辑后用 go test ./internal/render -update 重新生成 testdata 里的基准图
+var update = flag.Bool("update", false, "重新生成 testdata 里的基准图")
+
+// testGrid 固定的 3x6 字符画：带颜色、空格和一个暗色字符 (不画阴影)
+func testGrid() [][]entity.CharData {
+	red := color.RGBA{200, 40, 40, 255}
+	green := color.RGBA{40, 200, 80, 255}
+	dark := color.RGBA{20, 20, 30, 255}
+	row := func(s string, c color.Color) []entity.CharData {
+		var out []entity.CharData
+		for _, r := range s {
+			out = append(out, entity.CharData{OriginalChar: string(r), Char: string(r), Color: c})
+		}
+		return out
+	}
+	return [][]entity.CharData{
+		row(" /\\_/\\", red),
+		row("( o.o)", green),
+		row(" > ^ <", dark),
+	}
+}
+
+// testProcs 固定的进程排行：CPU 与内存各两名
+func testProcs() monitor.TopProcs {
+	return monitor.TopProcs{
+		ByCPU: []monitor.ProcInfo{{PID: 4242, Name: "cc1plus", CPU: 97.5}, {PID: 17, Name: "node", CPU: 12}},
+		ByMem: []monitor.ProcInfo{{PID: 17, Name: "node", Mem: 35.2}, {PID: 4242, Name: "cc1plus", Mem: 8}},
+	}
+}
+
+func loadTestFonts(t *testing.T) Fonts {
+	t.Helper()
+	fonts, err := LoadFonts(filepath.Join("..", "..", "assets", "PixelOperatorMono.ttf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fonts
+}
+
+func TestSnapshotGolden(t *testing.T) {
+	fonts := loadTestFonts(t)
+	th := theme.Default()
+
+	tests := []struct {
+		name  string
+		frame Frame
+	}{
+		{"pet_color", Frame{Grid: testGrid(), Style: PetStyle{Theme: th, ShowColor: true}}},
+		{"pet_mono", Frame{Grid: testGrid(), Style: PetStyle{Theme: th}}},
+		{"pet_stressed", Frame{Grid: testGrid(), Style: PetStyle{Theme: th, ShowColor: true, Stressed: true}}},
+		{"pet_hires", Frame{Grid: testGrid(), Style: PetStyle{Mode: 1, Theme: th, ShowColor: true}}},
+		{"hud", Frame{
+			Grid:  testGrid(),
+			Style: PetStyle{Theme: th, ShowColor: true},
+			HUD:   []string{"CPU 42%", "MEM 63%", "NET 1.2M", "DISK 80%"}, // 第 4 行压在宠物上
+		}},
+		{"bubble", Frame{
+			Grid:   testGrid(),
+			Style:  PetStyle{Theme: th, ShowColor: true},
+			Bubble: "disk almost full, please clean up /var/log",
+		}},
+		{"job", Frame{
+			Grid:  testGrid(),
+			Style: PetStyle{Theme: th, ShowColor: true},
+			Job:   []string{"[|] make test", "ok  0xPet/internal/hud"},
+		}},
+		{"procs", Frame{
+			Grid:  testGrid(),
+			Style: PetStyle{Theme: th, ShowColor: true, Stressed: true},
+			Procs: &Procs{Top: testProcs()},
+		}},
+		{"procs_expanded", Frame{
+			Grid:  testGrid(),
+			Style: PetStyle{Theme: th, ShowColor: true, Stressed: true},
+			Procs: &Procs{Top: testProcs(), Expanded: true},
+		}},
+		{"menu", Frame{
+			Grid:  testGrid(),
+			Style: PetStyle{Theme: th, ShowColor: true},
+			Menu:  &MenuState{ShowColor: true, Mode: 2, Profile: "work", Theme: th.Name},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkGolden(t, tt.name, Snapshot(fonts, tt.frame))
+		})
+	}
+}
+
+// checkGolden 把 got 与 testdata/<name>.png 逐像素比较；两边都经过一次 PNG 编解码，避免预乘转换带来的误差
+func checkGolden(t *testing.T, name string, got *image.RGBA) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".png")
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, got); err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("读取基准图失败 (用 -update 生成): %v", err)
+	}
+	want, err := decodeRGBA(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	have, err := decodeRGBA(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want.Bounds() != have.Bounds() {
+		t.Fatalf("尺寸 = %v, 基准图 %v", have.Bounds(), want.Bounds())
+	}
+	if !bytes.Equal(want.Pix, have.Pix) {
+		diff := 0
+		for i := 0; i < len(want.Pix); i += 4 {
+			if !bytes.Equal(want.Pix[i:i+4], have.Pix[i:i+4]) {
+				diff++
+			}
+		}
+		out := filepath.Join(t.TempDir(), name+".png")
+		_ = os.WriteFile(out, buf.Bytes(), 0o644)
+		t.Errorf("%d 个像素与 %s 不同，实际输出: %s", diff, path, out)
+	}
+}
+
+func decodeRGBA(data []byte) (*image.RGBA, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	out := image.NewRGBA(img.Bounds())
+	draw.Draw(out, out.Bounds(), img, img.Bounds().Min, draw.Src)
+	return out, nil
+}
@@ -0,0 +1,98 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"0xPet/internal/entity"
+	"0xPet/internal/monitor"
+	"0xPet/internal/theme"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// Image 软件渲染：把文字与矩形光栅化到 image.RGBA 上，不需要 GPU 和窗口
+type Image struct {
+	*image.RGBA
+}
+
+// NewImage 创建一张 w x h 的透明画布
+func NewImage(w, h int) *Image {
+	return &Image{image.NewRGBA(image.Rect(0, 0, w, h))}
+}
+
+func (m *Image) Text(s string, face font.Face, x, y int, c color.Color) {
+	d := font.Drawer{Dst: m.RGBA, Src: image.NewUniform(c), Face: face, Dot: fixed.P(x, y)}
+	d.DrawString(s)
+}
+
+func (m *Image) FillRect(x, y, w, h float32, c color.Color) {
+	r := image.Rect(int(math.Floor(float64(x))), int(math.Floor(float64(y))),
+		int(math.Ceil(float64(x+w))), int(math.Ceil(float64(y+h))))
+	draw.Draw(m.RGBA, r, image.NewUniform(c), image.Point{}, draw.Over)
+}
+
+// Frame 一帧完整画面的内容，和窗口里的布局一致：HUD 在顶部，宠物在下面，菜单在右侧
+type Frame struct {
+	Grid   [][]entity.CharData
+	Style  PetStyle
+	HUD    []string   // 为空时不画 HUD
+	Procs  *Procs     // 为 nil 时不画进程排行面板
+	Bubble string     // 为空时不画气泡
+	Job    []string   // 为空时不画任务框
+	Menu   *MenuState // 为 nil 时不画菜单
+}
+
+// Procs 进程排行面板的内容
+type Procs struct {
+	Top      monitor.TopProcs
+	Expanded bool // 鼠标悬停时展开完整排行
+}
+
+// Snapshot 用软件渲染画出一帧，适合截图和比对；宠物与菜单先各自画在自己的画布上再贴上去，裁剪效果和窗口里一样
+func Snapshot(fonts Fonts, f Frame) *image.RGBA {
+	if f.Style.Theme == nil {
+		f.Style.Theme = theme.Default()
+	}
+	petW, petH := PetSize(f.Grid, f.Style.Mode)
+	w, h := petW, petH+HUDHeight
+	if f.Menu != nil {
+		w += MenuWidth
+		if h < MinMenuH {
+			h = MinMenuH
+		}
+	}
+	out := NewImage(w, h)
+
+	// 1. 宠物
+	pet := NewImage(petW, petH)
+	Pet(pet, fonts, f.Grid, f.Style)
+	draw.Draw(out.RGBA, image.Rect(0, HUDHeight, petW, HUDHeight+petH), pet, image.Point{}, draw.Over)
+
+	// 2. HUD
+	if len(f.HUD) > 0 {
+		HUD(out, fonts, f.HUD, petW, f.Style.Theme)
+	}
+
+	// 3. 宠物上的叠加层，顺序和窗口里一致：进程排行、气泡、任务框
+	if f.Procs != nil {
+		ProcPanel(out, fonts, f.Procs.Top, f.Procs.Expanded, HUDHeight, petW, f.Style.Theme)
+	}
+	if f.Bubble != "" {
+		Bubble(out, fonts, f.Bubble, HUDHeight, petW, petH, f.Style.Theme)
+	}
+	if len(f.Job) > 0 {
+		Job(out, fonts, f.Job, HUDHeight, petW, petH, f.Style.Theme)
+	}
+
+	// 4. 菜单
+	if f.Menu != nil {
+		menu := NewImage(MenuWidth, h)
+		Menu(menu, fonts, *f.Menu, h, f.Style.Theme)
+		draw.Draw(out.RGBA, image.Rect(petW, 0, w, h), menu, image.Point{}, draw.Over)
+	}
+	return out.RGBA
+}
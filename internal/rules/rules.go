@@ -0,0 +1,274 @@
+// Package rules provides a declarative alert engine evaluated against monitor history
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"0xPet/config"
+	"0xPet/internal/entity"
+	"0xPet/internal/monitor"
+)
+
+// CheckExpr 检查规则表达式能否解析，供 config.Validator 使用
+func CheckExpr(expr string) error {
+	_, err := ParseCondition(expr)
+	return err
+}
+
+// Condition 解析后的表达式，比如 "cpu > 85 for 10s"
+type Condition struct {
+	Metric    string        // 指标名，和 monitor.Metrics.Values 的键一致
+	Op        string        // 比较符：> >= < <= == !=
+	Threshold float64       // 阈值
+	For       time.Duration // 需要持续满足的时长，0 表示只看最新样本
+}
+
+// MaxFor for 允许的最长时长：判断 "持续" 需要窗口之前还有更早的样本，历史只保留 monitor.HistorySpan
+const MaxFor = monitor.HistorySpan / 2
+
+// MaxAge 最新样本超过这么久就不再参与判断 (远程失联、插件出错退出 Custom 之后不会一直按旧值告警)
+const MaxAge = monitor.StaleAfter
+
+// Rule 一条告警规则：条件 + 触发时的宠物反应
+type Rule struct {
+	Name     string
+	Expr     string
+	Cond     Condition
+	Reaction entity.Reaction
+	Cooldown time.Duration // 两次触发之间的最短间隔
+}
+
+// Status 规则的生命周期状态
+type Status int
+
+const (
+	Inactive Status = iota // 从未触发，或已恢复后重新等待
+	Firing                 // 条件成立，正在告警
+	Resolved               // 刚从告警中恢复
+)
+
+func (s Status) String() string {
+	switch s {
+	case Firing:
+		return "firing"
+	case Resolved:
+		return "resolved"
+	default:
+		return "inactive"
+	}
+}
+
+// Event 规则状态变化事件
+type Event struct {
+	Rule   *Rule
+	Status Status // Firing 或 Resolved
+	Value  float64
+	Time   time.Time
+}
+
+// History 规则引擎需要的历史查询能力 (monitor.History 天然满足)
+type History interface {
+	Last(key string) (monitor.Sample, bool)
+	Window(key string, now time.Time, d time.Duration) []monitor.Sample
+}
+
+// ParseCondition 解析 "<指标> <比较符> <阈值> [for <时长>]"
+func ParseCondition(expr string) (Condition, error) {
+	fields := strings.Fields(expr)
+	var c Condition
+
+	switch {
+	case len(fields) == 3:
+	case len(fields) == 5 && fields[3] == "for":
+		d, err := time.ParseDuration(fields[4])
+		if err != nil || d < 0 {
+			return c, fmt.Errorf("规则 %q: 无效的持续时间 %q", expr, fields[4])
+		}
+		if d > MaxFor {
+			return c, fmt.Errorf("规则 %q: 持续时间不能超过 %v (历史只保留约 %v)", expr, MaxFor, monitor.HistorySpan)
+		}
+		c.For = d
+	default:
+		return c, fmt.Errorf("规则 %q: 格式应为 \"<指标> <比较符> <阈值> [for <时长>]\"", expr)
+	}
+
+	c.Metric = fields[0]
+	c.Op = fields[1]
+	switch c.Op {
+	case ">", ">=", "<", "<=", "==", "!=":
+	default:
+		return c, fmt.Errorf("规则 %q: 未知的比较符 %q", expr, c.Op)
+	}
+
+	v, err := strconv.ParseFloat(fields[2], 64)
+	if err != nil {
+		return c, fmt.Errorf("规则 %q: 无效的阈值 %q", expr, fields[2])
+	}
+	c.Threshold = v
+	return c, nil
+}
+
+// Match 判断单个数值是否满足条件
+func (c Condition) Match(v float64) bool {
+	switch c.Op {
+	case ">":
+		return v > c.Threshold
+	case ">=":
+		return v >= c.Threshold
+	case "<":
+		return v < c.Threshold
+	case "<=":
+		return v <= c.Threshold
+	case "==":
+		return v == c.Threshold
+	case "!=":
+		return v != c.Threshold
+	}
+	return false
+}
+
+// Holds 根据历史判断条件在 now 时刻是否成立，返回用于展示的最新值
+// 最新样本超过 MaxAge 时视为没有数据；带 for 的条件要求窗口内所有样本都满足，
+// 且窗口起点之前还有样本 (历史数据至少覆盖整个窗口)
+func (c Condition) Holds(h History, now time.Time) (bool, float64) {
+	last, ok := h.Last(c.Metric)
+	if !ok || now.Sub(last.Time) > MaxAge {
+		return false, last.Value
+	}
+	if c.For <= 0 {
+		return c.Match(last.Value), last.Value
+	}
+
+	samples := h.Window(c.Metric, now, c.For)
+	// 窗口之前必须还有更早的样本，否则说明数据还不够 "持续" 这么久；
+	// 窗口比采样间隔还短时里面可能一个样本都没有，这时最新样本的值一直延续到 now
+	if len(h.Window(c.Metric, now, c.For+MaxAge)) == len(samples) {
+		return false, last.Value
+	}
+	for _, s := range samples {
+		if !c.Match(s.Value) {
+			return false, last.Value
+		}
+	}
+	return c.Match(last.Value), last.Value
+}
+
+// ruleState 单条规则的运行时状态
+type ruleState struct {
+	status    Status
+	lastFired time.Time
+}
+
+// Engine 规则引擎：持有规则列表和每条规则的生命周期状态；
+// 监控协程调用 Evaluate 的同时，主循环 (热重载) 可以调用 Status
+type Engine struct {
+	rules []*Rule
+
+	mu     sync.Mutex
+	states []ruleState
+}
+
+// NewEngine 用一组已解析的规则创建引擎
+func NewEngine(rules []*Rule) *Engine {
+	return &Engine{rules: rules, states: make([]ruleState, len(rules))}
+}
+
+// Rules 返回引擎中的规则 (创建后不再修改)
+func (e *Engine) Rules() []*Rule { return e.rules }
+
+// Status 返回第 i 条规则当前的状态
+func (e *Engine) Status(i int) Status {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.states[i].status
+}
+
+// Evaluate 在 now 时刻评估所有规则，返回状态发生变化的事件
+func (e *Engine) Evaluate(h History, now time.Time) []Event {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var events []Event
+	for i, r := range e.rules {
+		st := &e.states[i]
+		holds, value := r.Cond.Holds(h, now)
+
+		switch {
+		case holds && st.status != Firing:
+			// 冷却期内不重复告警，保持原状态等下一轮
+			if !st.lastFired.IsZero() && now.Sub(st.lastFired) < r.Cooldown {
+				continue
+			}
+			st.status = Firing
+			st.lastFired = now
+			events = append(events, Event{Rule: r, Status: Firing, Value: value, Time: now})
+		case !holds && st.status == Firing:
+			st.status = Resolved
+			events = append(events, Event{Rule: r, Status: Resolved, Value: value, Time: now})
+		case !holds && st.status == Resolved:
+			st.status = Inactive
+		}
+	}
+	return events
+}
+
+// Reaction 把事件翻译成宠物反应：告警时套用规则的反应，恢复时撤销
+func (ev Event) Reaction() entity.Reaction {
+	if ev.Status == Resolved {
+		return entity.Reaction{Source: ev.Rule.Reaction.Source, Clear: true}
+	}
+	r := ev.Rule.Reaction
+	r.Bubble = strings.NewReplacer(
+		"{value}", strconv.FormatFloat(ev.Value, 'f', 1, 64),
+		"{metric}", ev.Rule.Cond.Metric,
+		"{name}", ev.Rule.Name,
+	).Replace(r.Bubble)
+	return r
+}
+
+// Compile 把配置里的规则解析成可执行的规则，任意一条出错都整体返回错误
+func Compile(cfgs []config.RuleConfig) ([]*Rule, error) {
+	out := make([]*Rule, 0, len(cfgs))
+	for _, rc := range cfgs {
+		cond, err := ParseCondition(rc.Expr)
+		if err != nil {
+			return nil, err
+		}
+
+		name := rc.Name
+		if name == "" {
+			name = rc.Expr
+		}
+		r := &Rule{
+			Name: name,
+			Expr: rc.Expr,
+			Cond: cond,
+			Reaction: entity.Reaction{
+				Source: "rule:" + name,
+				State:  rc.State,
+				Bubble: rc.Bubble,
+				Glitch: rc.Glitch,
+			},
+		}
+
+		if rc.Color != "" {
+			c, err := entity.ParseColor(rc.Color)
+			if err != nil {
+				return nil, fmt.Errorf("规则 %q: %w", name, err)
+			}
+			r.Reaction.Color = c
+		}
+		if rc.Cooldown != "" {
+			d, err := time.ParseDuration(rc.Cooldown)
+			if err != nil || d < 0 {
+				return nil, fmt.Errorf("规则 %q: 无效的冷却时间 %q", name, rc.Cooldown)
+			}
+			r.Cooldown = d
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
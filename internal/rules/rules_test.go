@@ -0,0 +1,244 @@
+package rules
+
+import (
+	"testing"
+	"time"
+
+	"0xPet/config"
+	"0xPet/internal/monitor"
+)
+
+// fakeHistory 内存里的历史记录：每个指标一串按时间排好的样本
+type fakeHistory map[string][]monitor.Sample
+
+func (h fakeHistory) Last(key string) (monitor.Sample, bool) {
+	s := h[key]
+	if len(s) == 0 {
+		return monitor.Sample{}, false
+	}
+	return s[len(s)-1], true
+}
+
+func (h fakeHistory) Window(key string, now time.Time, d time.Duration) []monitor.Sample {
+	var out []monitor.Sample
+	for _, s := range h[key] {
+		if s.Time.After(now.Add(-d)) && !s.Time.After(now) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+var t0 = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// series 从 t0 开始每 2 秒一个样本
+func series(values ...float64) []monitor.Sample {
+	out := make([]monitor.Sample, len(values))
+	for i, v := range values {
+		out[i] = monitor.Sample{Time: t0.Add(time.Duration(i) * monitor.Interval), Value: v}
+	}
+	return out
+}
+
+// full 填满整个全局历史的样本
+func full(v float64) []float64 {
+	values := make([]float64, monitor.DefaultHistorySize)
+	for i := range values {
+		values[i] = v
+	}
+	return values
+}
+
+// end series 最后一个样本的时间
+func end(s []monitor.Sample) time.Time {
+	return s[len(s)-1].Time
+}
+
+func TestParseCondition(t *testing.T) {
+	tests := []struct {
+		expr string
+		want Condition
+		err  bool
+	}{
+		{expr: "cpu > 85", want: Condition{Metric: "cpu", Op: ">", Threshold: 85}},
+		{expr: "disk:/ >= 95.5", want: Condition{Metric: "disk:/", Op: ">=", Threshold: 95.5}},
+		{expr: "swap > 20 for 1m", want: Condition{Metric: "swap", Op: ">", Threshold: 20, For: time.Minute}},
+		{expr: "battery != 0", want: Condition{Metric: "battery", Op: "!=", Threshold: 0}},
+		{expr: "cpu", err: true},
+		{expr: "cpu => 80", err: true},
+		{expr: "cpu > high", err: true},
+		{expr: "cpu > 80 for", err: true},
+		{expr: "cpu > 80 during 10s", err: true},
+		{expr: "cpu > 80 for -1s", err: true},
+		{expr: "cpu > 80 for 5m", err: true}, // 超过历史能覆盖的范围，永远不会成立
+	}
+	for _, tt := range tests {
+		got, err := ParseCondition(tt.expr)
+		if (err != nil) != tt.err {
+			t.Errorf("ParseCondition(%q) error = %v, want error %v", tt.expr, err, tt.err)
+			continue
+		}
+		if !tt.err && got != tt.want {
+			t.Errorf("ParseCondition(%q) = %+v, want %+v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestConditionMatch(t *testing.T) {
+	tests := []struct {
+		op   string
+		v    float64
+		want bool
+	}{
+		{">", 81, true}, {">", 80, false},
+		{">=", 80, true}, {">=", 79, false},
+		{"<", 79, true}, {"<", 80, false},
+		{"<=", 80, true}, {"<=", 81, false},
+		{"==", 80, true}, {"==", 81, false},
+		{"!=", 81, true}, {"!=", 80, false},
+	}
+	for _, tt := range tests {
+		c := Condition{Metric: "cpu", Op: tt.op, Threshold: 80}
+		if got := c.Match(tt.v); got != tt.want {
+			t.Errorf("%v %s 80 = %v, want %v", tt.v, tt.op, got, tt.want)
+		}
+	}
+}
+
+func TestConditionHolds(t *testing.T) {
+	tests := []struct {
+		name   string
+		expr   string
+		values []float64
+		after  time.Duration // 最后一个样本之后多久评估
+		want   bool
+	}{
+		{"latest matches", "cpu > 80", []float64{10, 90}, 0, true},
+		{"latest does not match", "cpu > 80", []float64{90, 10}, 0, false},
+		{"no data", "mem > 80", nil, 0, false},
+		{"sustained", "cpu > 80 for 10s", []float64{10, 90, 90, 90, 90, 90, 90}, 0, true},
+		{"dip inside window", "cpu > 80 for 10s", []float64{10, 90, 90, 50, 90, 90, 90}, 0, false},
+		{"not enough history", "cpu > 80 for 10s", []float64{90, 90, 90}, 0, false},
+		{"window shorter than interval", "cpu > 80 for 1s", []float64{10, 90}, 0, true},
+		{"window between samples", "cpu > 80 for 1s", []float64{10, 90}, 1500 * time.Millisecond, true},
+		{"longest window", "cpu > 80 for 2m30s", full(90), 0, true},
+		{"stale latest", "cpu > 80", []float64{90}, MaxAge + time.Second, false},
+		{"stale sustained", "cpu > 80 for 10s", []float64{10, 90, 90, 90, 90, 90, 90}, MaxAge + time.Second, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseCondition(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := fakeHistory{}
+			now := t0
+			if len(tt.values) > 0 {
+				h["cpu"] = series(tt.values...)
+				now = end(h["cpu"]).Add(tt.after)
+			}
+			if got, _ := c.Holds(h, now); got != tt.want {
+				t.Errorf("Holds = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEngineLifecycle(t *testing.T) {
+	compiled, err := Compile([]config.RuleConfig{
+		{Name: "hot", Expr: "cpu > 80", State: "stressed", Bubble: "{metric} at {value}", Cooldown: "1m"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEngine(compiled)
+	h := fakeHistory{}
+
+	steps := []struct {
+		at     time.Duration
+		value  float64
+		event  Status // Inactive 表示这一步不应该有事件
+		status Status
+	}{
+		{0, 50, Inactive, Inactive},
+		{2 * time.Second, 90, Firing, Firing},
+		{4 * time.Second, 95, Inactive, Firing},
+		{6 * time.Second, 40, Resolved, Resolved},
+		{8 * time.Second, 40, Inactive, Inactive},
+		{10 * time.Second, 90, Inactive, Inactive}, // 冷却期内不重复告警
+		{70 * time.Second, 90, Firing, Firing},
+	}
+	for _, st := range steps {
+		now := t0.Add(st.at)
+		h["cpu"] = append(h["cpu"], monitor.Sample{Time: now, Value: st.value})
+		events := e.Evaluate(h, now)
+
+		switch {
+		case st.event == Inactive && len(events) != 0:
+			t.Errorf("%v: unexpected events %+v", st.at, events)
+		case st.event != Inactive && (len(events) != 1 || events[0].Status != st.event):
+			t.Errorf("%v: events = %+v, want one %v", st.at, events, st.event)
+		}
+		if got := e.Status(0); got != st.status {
+			t.Errorf("%v: status = %v, want %v", st.at, got, st.status)
+		}
+	}
+}
+
+func TestEventReaction(t *testing.T) {
+	compiled, err := Compile([]config.RuleConfig{
+		{Name: "disk", Expr: "disk:/ > 95", State: "stressed", Color: "#ff0000", Bubble: "{name}: {metric} {value}%"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := compiled[0]
+
+	fired := Event{Rule: r, Status: Firing, Value: 97.25}.Reaction()
+	if fired.Source != "rule:disk" || fired.State != "stressed" || fired.Color == nil {
+		t.Errorf("firing reaction = %+v", fired)
+	}
+	if want := "disk: disk:/ 97.2%"; fired.Bubble != want {
+		t.Errorf("bubble = %q, want %q", fired.Bubble, want)
+	}
+
+	resolved := Event{Rule: r, Status: Resolved}.Reaction()
+	if !resolved.Clear || resolved.Source != "rule:disk" {
+		t.Errorf("resolved reaction = %+v", resolved)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []config.RuleConfig{
+		{Expr: "cpu >"},
+		{Expr: "cpu > 80", Color: "red-ish"},
+		{Expr: "cpu > 80", Cooldown: "soon"},
+	}
+	for _, rc := range tests {
+		if _, err := Compile([]config.RuleConfig{rc}); err == nil {
+			t.Errorf("Compile(%+v) succeeded, want error", rc)
+		}
+	}
+}
+
+// TestEngineConcurrentStatus 热重载时主循环读 Status，监控协程同时在 Evaluate (用 -race 运行才有意义)
+func TestEngineConcurrentStatus(t *testing.T) {
+	compiled, err := Compile([]config.RuleConfig{{Expr: "cpu > 80"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEngine(compiled)
+	h := fakeHistory{"cpu": series(10, 90, 10, 90, 10, 90)}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			e.Evaluate(h, t0.Add(time.Duration(i%6)*monitor.Interval))
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		e.Status(0)
+	}
+	<-done
+}
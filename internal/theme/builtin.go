@@ -0,0 +1,156 @@
+package theme
+
+// builtins 内置主题，按菜单里的切换顺序排列；default 就是原来写死在代码里的配色
+var builtins = []Theme{
+	{
+		Name:         DefaultName,
+		Mono:         Color{0, 255, 0, 255},
+		Stress:       Color{255, 50, 50, 255},
+		ColorBoost:   1.3,
+		ShadowAlpha:  140,
+		Glitch:       Color{0, 255, 255, 255},
+		HUD:          Color{255, 255, 0, 255},
+		HUDBg:        Color{0, 0, 0, 170},
+		Panel:        Color{255, 120, 120, 255},
+		PanelBg:      Color{0, 0, 0, 190},
+		Bubble:       Color{10, 10, 10, 255},
+		BubbleBg:     Color{240, 240, 240, 230},
+		BubbleBorder: Color{0, 0, 0, 255},
+		MenuBg:       Color{8, 8, 12, 230},
+		Accent:       Color{0, 255, 255, 255},
+		MenuText:     Color{180, 180, 190, 255},
+		MenuValue:    Color{220, 220, 80, 255},
+		MenuDanger:   Color{255, 100, 100, 255},
+	},
+	{
+		// 老式 P1 荧光屏：全部是绿色，靠亮度区分
+		Name:         "phosphor",
+		Mono:         Color{51, 255, 102, 255},
+		Stress:       Color{200, 255, 200, 255},
+		ColorBoost:   1.3,
+		ShadowAlpha:  160,
+		Glitch:       Color{180, 255, 180, 255},
+		HUD:          Color{120, 255, 140, 255},
+		HUDBg:        Color{0, 12, 0, 180},
+		Panel:        Color{170, 255, 170, 255},
+		PanelBg:      Color{0, 12, 0, 200},
+		Bubble:       Color{0, 20, 0, 255},
+		BubbleBg:     Color{120, 255, 140, 230},
+		BubbleBorder: Color{0, 60, 0, 255},
+		MenuBg:       Color{0, 10, 2, 235},
+		Accent:       Color{51, 255, 102, 255},
+		MenuText:     Color{30, 150, 60, 255},
+		MenuValue:    Color{150, 255, 160, 255},
+		MenuDanger:   Color{220, 255, 220, 255},
+	},
+	{
+		// 琥珀色终端
+		Name:         "amber",
+		Mono:         Color{255, 176, 0, 255},
+		Stress:       Color{255, 80, 0, 255},
+		ColorBoost:   1.2,
+		ShadowAlpha:  150,
+		Glitch:       Color{255, 220, 120, 255},
+		HUD:          Color{255, 200, 60, 255},
+		HUDBg:        Color{16, 8, 0, 180},
+		Panel:        Color{255, 140, 40, 255},
+		PanelBg:      Color{16, 8, 0, 200},
+		Bubble:       Color{30, 16, 0, 255},
+		BubbleBg:     Color{255, 200, 100, 230},
+		BubbleBorder: Color{90, 50, 0, 255},
+		MenuBg:       Color{16, 8, 0, 235},
+		Accent:       Color{255, 176, 0, 255},
+		MenuText:     Color{170, 110, 20, 255},
+		MenuValue:    Color{255, 210, 120, 255},
+		MenuDanger:   Color{255, 90, 40, 255},
+	},
+	{
+		// 冷色调
+		Name:         "ice",
+		Mono:         Color{160, 220, 255, 255},
+		Stress:       Color{255, 90, 140, 255},
+		ColorBoost:   1.2,
+		ShadowAlpha:  120,
+		Glitch:       Color{255, 255, 255, 255},
+		HUD:          Color{200, 240, 255, 255},
+		HUDBg:        Color{6, 12, 20, 180},
+		Panel:        Color{255, 150, 180, 255},
+		PanelBg:      Color{6, 12, 20, 200},
+		Bubble:       Color{10, 30, 50, 255},
+		BubbleBg:     Color{225, 245, 255, 230},
+		BubbleBorder: Color{60, 120, 170, 255},
+		MenuBg:       Color{6, 12, 20, 230},
+		Accent:       Color{120, 200, 255, 255},
+		MenuText:     Color{110, 140, 170, 255},
+		MenuValue:    Color{190, 230, 255, 255},
+		MenuDanger:   Color{255, 110, 150, 255},
+	},
+	{
+		// 高对比度：不透明的纯黑底、纯色字、投影拉满
+		Name:         "high-contrast",
+		Mono:         Color{255, 255, 255, 255},
+		Stress:       Color{255, 0, 0, 255},
+		ColorBoost:   1.5,
+		ShadowAlpha:  255,
+		Glitch:       Color{255, 255, 0, 255},
+		HUD:          Color{255, 255, 0, 255},
+		HUDBg:        Color{0, 0, 0, 255},
+		Panel:        Color{255, 255, 255, 255},
+		PanelBg:      Color{0, 0, 0, 255},
+		Bubble:       Color{0, 0, 0, 255},
+		BubbleBg:     Color{255, 255, 255, 255},
+		BubbleBorder: Color{0, 0, 0, 255},
+		MenuBg:       Color{0, 0, 0, 255},
+		Accent:       Color{255, 255, 0, 255},
+		MenuText:     Color{255, 255, 255, 255},
+		MenuValue:    Color{0, 255, 255, 255},
+		MenuDanger:   Color{255, 0, 0, 255},
+	},
+	{
+		// 色盲友好：取自 Okabe-Ito 配色，平时是蓝色，高压是橙色，不靠红绿区分
+		Name:         "colorblind",
+		Mono:         Color{86, 180, 233, 255},
+		Stress:       Color{230, 159, 0, 255},
+		ColorBoost:   1.3,
+		ShadowAlpha:  140,
+		Glitch:       Color{204, 121, 167, 255},
+		HUD:          Color{240, 228, 66, 255},
+		HUDBg:        Color{0, 0, 0, 170},
+		Panel:        Color{230, 159, 0, 255},
+		PanelBg:      Color{0, 0, 0, 190},
+		Bubble:       Color{10, 10, 10, 255},
+		BubbleBg:     Color{240, 240, 240, 230},
+		BubbleBorder: Color{0, 114, 178, 255},
+		MenuBg:       Color{8, 8, 12, 230},
+		Accent:       Color{86, 180, 233, 255},
+		MenuText:     Color{180, 180, 190, 255},
+		MenuValue:    Color{240, 228, 66, 255},
+		MenuDanger:   Color{213, 94, 0, 255},
+	},
+}
+
+// Default 默认主题
+func Default() *Theme {
+	t, _ := builtin(DefaultName)
+	return t
+}
+
+// BuiltinNames 内置主题的名字
+func BuiltinNames() []string {
+	names := make([]string, len(builtins))
+	for i, t := range builtins {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// builtin 返回内置主题的副本
+func builtin(name string) (*Theme, bool) {
+	for _, t := range builtins {
+		if t.Name == name {
+			t := t
+			return &t, true
+		}
+	}
+	return nil, false
+}
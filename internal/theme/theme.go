@@ -0,0 +1,172 @@
+// Package theme provides the color schemes used to draw the pet, the HUD and the menu,
+// both the built-in ones and user themes loaded from JSON files
+package theme
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"0xPet/internal/paths"
+)
+
+// DefaultName 配置里不写 theme 时使用的主题
+const DefaultName = "default"
+
+// Color JSON 里写成 "#rrggbb" 或 "#rrggbbaa"；和 CSS 一样，颜色分量没有按透明度预乘
+type Color color.NRGBA
+
+// RGBA 转成绘图用的 (预乘透明度的) color.RGBA
+func (c Color) RGBA() color.RGBA {
+	return color.RGBAModel.Convert(color.NRGBA(c)).(color.RGBA)
+}
+
+func (c Color) MarshalJSON() ([]byte, error) {
+	s := fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+	if c.A != 255 {
+		s += fmt.Sprintf("%02x", c.A)
+	}
+	return json.Marshal(s)
+}
+
+func (c *Color) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("颜色应该写成 \"#rrggbb\" 或 \"#rrggbbaa\" 字符串")
+	}
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) != 6 && len(hex) != 8 || len(hex) == len(s) {
+		return fmt.Errorf("颜色 %q 应该写成 \"#rrggbb\" 或 \"#rrggbbaa\"", s)
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return fmt.Errorf("颜色 %q 不是有效的十六进制", s)
+	}
+	*c = Color{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}
+	return nil
+}
+
+// Theme 一套配色：宠物字符、HUD、面板、气泡与右键菜单
+type Theme struct {
+	Name string `json:"name,omitempty"` // 显示名，留空时用文件名
+	Base string `json:"base,omitempty"` // 用户主题只写想改的颜色，其余沿用这个内置主题，默认 "default"
+
+	// 宠物字符
+	Mono        Color   `json:"mono"`         // 关闭彩色时的字符颜色
+	Stress      Color   `json:"stress"`       // 高压时的字符颜色
+	ColorBoost  float64 `json:"color_boost"`  // 彩色模式下原图颜色的提亮倍数
+	ShadowAlpha uint8   `json:"shadow_alpha"` // 字符投影的不透明度，0 表示不画投影
+	Glitch      Color   `json:"glitch"`       // 乱码爆发的字符颜色
+
+	// HUD 与进程面板
+	HUD     Color `json:"hud"`
+	HUDBg   Color `json:"hud_bg"` // HUD 压在宠物上的部分垫的底色
+	Panel   Color `json:"panel"`  // 进程排行、命令进度的文字
+	PanelBg Color `json:"panel_bg"`
+
+	// 气泡
+	Bubble       Color `json:"bubble"`
+	BubbleBg     Color `json:"bubble_bg"`
+	BubbleBorder Color `json:"bubble_border"`
+
+	// 右键菜单
+	MenuBg     Color `json:"menu_bg"`
+	Accent     Color `json:"accent"`      // 菜单左边的竖线与打开的开关
+	MenuText   Color `json:"menu_text"`   // 关闭的开关
+	MenuValue  Color `json:"menu_value"`  // MODE / PROFILE / THEME 这类多选项
+	MenuDanger Color `json:"menu_danger"` // EXIT
+}
+
+// Check 检查主题能否加载，供 config.Validator 使用
+func Check(name string) error {
+	_, err := Load(name)
+	return err
+}
+
+// Load 按名字加载主题：先找用户主题目录下的 <name>.json，再找内置主题；name 为空时返回默认主题
+func Load(name string) (*Theme, error) {
+	if name == "" {
+		name = DefaultName
+	}
+	if strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return nil, fmt.Errorf("主题名 %q 无效", name)
+	}
+	data, err := os.ReadFile(filepath.Join(paths.ThemesDir(), name+".json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		if t, ok := builtin(name); ok {
+			return t, nil
+		}
+		return nil, fmt.Errorf("主题 %q 不存在，可选: %s", name, strings.Join(Names(), ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+	t, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("主题 %s: %w", filepath.Join(paths.ThemesDir(), name+".json"), err)
+	}
+	if t.Name == "" {
+		t.Name = name
+	}
+	return t, nil
+}
+
+// Parse 解析一个主题文件，没写的颜色取自 base 指定的内置主题
+func Parse(data []byte) (*Theme, error) {
+	var head struct {
+		Base string `json:"base"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return nil, err
+	}
+	if head.Base == "" {
+		head.Base = DefaultName
+	}
+	base, ok := builtin(head.Base)
+	if !ok {
+		return nil, fmt.Errorf("base: 内置主题 %q 不存在，可选: %s", head.Base, strings.Join(BuiltinNames(), ", "))
+	}
+
+	t := *base
+	t.Name = ""
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields() // 主题字段拼错了就报错，而不是悄悄用默认色
+	if err := dec.Decode(&t); err != nil {
+		return nil, err
+	}
+	if t.ColorBoost <= 0 {
+		return nil, fmt.Errorf("color_boost 必须大于 0")
+	}
+	return &t, nil
+}
+
+// Names 返回可选的主题名：内置主题在前，然后是用户主题目录下的文件 (按字母排序)
+func Names() []string {
+	names := BuiltinNames()
+	seen := map[string]bool{}
+	for _, n := range names {
+		seen[n] = true
+	}
+
+	files, _ := filepath.Glob(filepath.Join(paths.ThemesDir(), "*.json"))
+	var user []string
+	for _, f := range files {
+		name := strings.TrimSuffix(filepath.Base(f), ".json")
+		if !seen[name] {
+			user = append(user, name)
+		}
+	}
+	sort.Strings(user)
+	return append(names, user...)
+}
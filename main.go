@@ -1,29 +1,439 @@
 package main
 
 import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
 	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"0xPet/config"
+	"0xPet/internal/ascii"
+	"0xPet/internal/control"
+	"0xPet/internal/entity"
+	"0xPet/internal/fsutil"
+	"0xPet/internal/game"
+	"0xPet/internal/hud"
+	"0xPet/internal/input"
+	"0xPet/internal/monitor"
+	"0xPet/internal/paths"
+	"0xPet/internal/remote"
+	"0xPet/internal/render"
+	"0xPet/internal/rules"
+	"0xPet/internal/theme"
 
 	"github.com/hajimehoshi/ebiten/v2"
 )
 
-// 空白挂件，不执行任何逻辑，不绘制任何像素
-type BlankGame struct{}
+func main() {
+	// 【新增】规则表达式、状态名与主题的检查由各自的包提供，在读取任何配置之前交给 config
+	config.SetValidator(config.Validator{
+		Expr:  rules.CheckExpr,
+		State: entity.CheckState,
+		Theme: theme.Check,
+	})
+
+	// 【新增】子命令：agent 在无界面的机器上运行，把监控数据发给远程宠物
+	if len(os.Args) > 1 && os.Args[1] == "agent" {
+		if err := runAgent(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "agent:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// 【新增】子命令：watch 运行一条命令，并让宠物对它的结果做出反应
+	if len(os.Args) > 1 && os.Args[1] == "watch" {
+		code, err := runWatch(os.Args[2:])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "watch:", err)
+		}
+		os.Exit(code)
+	}
 
-func (g *BlankGame) Update() error             { return nil }
-func (g *BlankGame) Draw(screen *ebiten.Image) {}
-func (g *BlankGame) Layout(outsideWidth, outsideHeight int) (int, int) {
-	return 200, 200
+	// 【新增】子命令：config 检查配置文件或打印默认配置
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		code, err := runConfig(os.Args[2:])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "config:", err)
+		}
+		os.Exit(code)
+	}
+
+	// 【新增】子命令：render 不开窗口，用软件渲染把宠物画成 PNG
+	if len(os.Args) > 1 && os.Args[1] == "render" {
+		if err := runRender(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "render:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// 【修改】默认运行桌宠本体
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "0xpet:", err)
+		os.Exit(1)
+	}
 }
 
-func main() {
-	ebiten.SetWindowDecorated(false)
-	ebiten.SetScreenTransparent(true)
-	ebiten.SetWindowFloating(true)
+// run 运行 `0xpet [-config 文件] [-record 文件 | -replay 文件] [配置参数...]`：读取配置、初始化 Manager 并打开桌宠窗口，直到退出
+func run(args []string) error {
+	fs := flag.NewFlagSet("0xpet", flag.ContinueOnError)
+	configFile := fs.String("config", "", "配置文件路径")
+	record := fs.String("record", "", "把这次的鼠标键盘输入录制到文件，用于复现问题")
+	replay := fs.String("replay", "", "回放 -record 录下的输入，而不是读取真实的鼠标键盘")
+	flagOverrides := config.RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("未知的子命令 %q，可用: agent、watch、config、render", fs.Arg(0))
+	}
+	if *record != "" && *replay != "" {
+		return fmt.Errorf("-record 与 -replay 不能同时使用")
+	}
 
-	// 强制降频，切断所有渲染和逻辑更新压力
-	ebiten.SetTPS(5)
+	overrides, err := collectOverrides(flagOverrides())
+	if err != nil {
+		return err
+	}
+	g := &game.Manager{ConfigPath: config.ConfigPath(*configFile, paths.ConfigFile()), Overrides: overrides}
 
-	if err := ebiten.RunGame(&BlankGame{}); err != nil {
-		log.Fatal(err)
+	// 【新增】录制或回放输入
+	switch {
+	case *record != "":
+		f, err := os.Create(*record)
+		if err != nil {
+			return err
+		}
+		rec := input.NewRecorder(f, game.NativeWindow())
+		defer func() {
+			if err := rec.Close(); err != nil {
+				log.Println("录制失败:", err)
+			}
+			f.Close()
+		}()
+		g.Recorder = rec
+	case *replay != "":
+		f, err := os.Open(*replay)
+		if err != nil {
+			return err
+		}
+		rp, err := input.LoadReplay(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", *replay, err)
+		}
+		// 从录制开始时的窗口位置放起
+		game.NativeWindow().SetPosition(rp.Header.WindowX, rp.Header.WindowY)
+		g.Input = rp
+		g.Replaying = true
 	}
+
+	if err := g.Init(); err != nil {
+		return err
+	}
+	return runPet(g)
+}
+
+// runAgent 启动无界面的采样服务，直到收到 SIGINT/SIGTERM
+func runAgent(args []string) error {
+	fs := flag.NewFlagSet("agent", flag.ContinueOnError)
+	listen := fs.String("listen", "127.0.0.1:9465", "监听地址 (对外提供服务请改成 0.0.0.0:9465)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	monitor.Start()
+	agent := remote.NewAgent(*listen, monitor.Interval, monitor.GetMetrics)
+	if err := agent.Start(); err != nil {
+		return err
+	}
+	log.Printf("agent 已启动: http://%s%s (JSON), http://%s%s (SSE)",
+		agent.Addr(), remote.PathSample, agent.Addr(), remote.PathEvents)
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	<-sig
+	return agent.Close()
+}
+
+// runWatch 运行 `0xpet watch [-addr host:port] -- <命令>`，返回命令的退出码
+// 已有宠物在运行时把进度转发给它；否则在本进程里开一只宠物，展示完结果后自动关闭
+func runWatch(args []string) (int, error) {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	addr := fs.String("addr", "", "正在运行的宠物的控制端点，默认取配置里的 control_addr")
+	configFile := fs.String("config", "", "配置文件路径")
+	flagOverrides := config.RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return 2, err
+	}
+	command := fs.Args()
+	if len(command) == 0 {
+		return 2, fmt.Errorf("用法: 0xpet watch [-addr host:port] [配置参数...] -- <命令> [参数...]")
+	}
+
+	path := config.ConfigPath(*configFile, paths.ConfigFile())
+	overrides, err := collectOverrides(flagOverrides())
+	if err != nil {
+		return 2, err
+	}
+	if *addr == "" {
+		*addr = config.DefaultControlAddr
+		if layers, err := config.Resolve(path, overrides); err == nil {
+			*addr = layers.Effective.ControlAddr
+		}
+	}
+
+	job := &control.Job{Args: command, Stdout: os.Stdout, Stderr: os.Stderr}
+
+	// 1. 转发模式：交给已经在运行的宠物
+	client := control.NewClient(*addr)
+	if client.Ping() {
+		warned := false
+		job.OnEvent = func(ev control.JobEvent) {
+			if err := client.SendJob(ev); err != nil && !warned {
+				log.Println("无法通知宠物，命令继续运行:", err)
+				warned = true
+			}
+		}
+		return job.Run(), nil
+	}
+
+	// 2. 单次模式：自己开一只宠物，命令结束并展示完结果后退出
+	g := &game.Manager{ConfigPath: path, Overrides: overrides}
+	if err := g.Init(); err != nil {
+		return 1, err
+	}
+	job.OnEvent = g.HandleJob
+
+	done := make(chan int, 1)
+	go func() {
+		code := job.Run()
+		done <- code
+		hold := 5 * time.Second
+		if code != 0 {
+			hold = 15 * time.Second
+		}
+		g.ExitAfter(hold, code)
+	}()
+
+	if err := runPet(g); err != nil {
+		return 1, err
+	}
+	// 窗口被提前关掉时，仍然等命令跑完，保证退出码与命令一致
+	return <-done, nil
+}
+
+// collectOverrides 合并 OXPET_* 环境变量与命令行参数，命令行优先
+func collectOverrides(flagOverrides []config.Override) ([]config.Override, error) {
+	env, err := config.EnvOverrides(os.Environ())
+	if err != nil {
+		return nil, err
+	}
+	return append(env, flagOverrides...), nil
+}
+
+// runConfig 运行 `0xpet config validate|show|print-default`
+func runConfig(args []string) (int, error) {
+	usage := fmt.Errorf("用法: 0xpet config validate [-strict] [文件] | 0xpet config show [-config 文件] [配置参数...] | 0xpet config print-default")
+	if len(args) == 0 {
+		return 2, usage
+	}
+
+	switch args[0] {
+	case "print-default":
+		data, err := config.Marshal(config.NewDefault())
+		if err != nil {
+			return 1, err
+		}
+		fmt.Println(string(data))
+		return 0, nil
+
+	case "show":
+		// 列出每个字段的生效值以及它来自哪一层
+		fs := flag.NewFlagSet("config show", flag.ContinueOnError)
+		configFile := fs.String("config", "", "配置文件路径")
+		flagOverrides := config.RegisterFlags(fs)
+		if err := fs.Parse(args[1:]); err != nil {
+			return 2, err
+		}
+		overrides, err := collectOverrides(flagOverrides())
+		if err != nil {
+			return 2, err
+		}
+		layers, err := config.Resolve(config.ConfigPath(*configFile, paths.ConfigFile()), overrides)
+		if err != nil {
+			return 1, err
+		}
+		for _, key := range config.Keys() {
+			fmt.Printf("%-16s %-32s # %s\n", key, layers.Effective.Value(key), layers.Origin(key))
+		}
+		return 0, nil
+
+	case "validate":
+		fs := flag.NewFlagSet("config validate", flag.ContinueOnError)
+		strict := fs.Bool("strict", false, "有警告 (比如不认识的字段) 时也算失败")
+		if err := fs.Parse(args[1:]); err != nil {
+			return 2, err
+		}
+		file := config.ConfigPath("", paths.ConfigFile())
+		if fs.NArg() > 0 {
+			file = fs.Arg(0)
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return 1, err
+		}
+		_, probs := config.Check(data)
+		failed := false
+		for _, p := range probs {
+			fmt.Println(p.In(file))
+			if !p.Warning || *strict {
+				failed = true
+			}
+		}
+		if failed {
+			return 1, nil
+		}
+		fmt.Println(file + ": OK")
+		return 0, nil
+	}
+	return 2, usage
+}
+
+// runRender 运行 `0xpet render --png 文件 [-image 图片] [-hud] [-menu] [-stressed] [配置参数...]`，
+// 按配置 (主题、显示模式、字符集等) 画出和窗口里一样的一帧，不需要 GPU 和显示器
+func runRender(args []string) error {
+	fs := flag.NewFlagSet("render", flag.ContinueOnError)
+	out := fs.String("png", "", "输出的 PNG 文件 (必填)")
+	configFile := fs.String("config", "", "配置文件路径")
+	imagePath := fs.String("image", "", "宠物图片，默认取配置里的 image_path")
+	withHUD := fs.Bool("hud", false, "画上 HUD (采样一次本机指标)")
+	withMenu := fs.Bool("menu", false, "画上右键菜单")
+	stressed := fs.Bool("stressed", false, "按高压状态着色")
+	flagOverrides := config.RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *out == "" || fs.NArg() > 0 {
+		return fmt.Errorf("用法: 0xpet render --png 文件 [-image 图片] [-hud] [-menu] [-stressed] [配置参数...]")
+	}
+
+	// 1. 配置、主题与字体
+	overrides, err := collectOverrides(flagOverrides())
+	if err != nil {
+		return err
+	}
+	layers, err := config.Resolve(config.ConfigPath(*configFile, paths.ConfigFile()), overrides)
+	if err != nil {
+		return err
+	}
+	cfg := layers.Effective
+	th, err := theme.Load(cfg.Theme)
+	if err != nil {
+		log.Println("加载主题失败，使用默认主题:", err)
+		th = theme.Default()
+	}
+	fonts, err := render.LoadFonts(paths.Asset("PixelOperatorMono.ttf"))
+	if err != nil {
+		return err
+	}
+
+	// 2. 图片转字符画
+	src := *imagePath
+	if src == "" {
+		src = paths.ResolveImage(cfg.ImagePath)
+	}
+	if src == "" {
+		src = paths.Asset("idle.png")
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%s: %w", src, err)
+	}
+	cols, _, _ := render.Cells(cfg.DisplayMode)
+	_, grid := ascii.ConvertRamp(ascii.Crop(img), cols, cfg.Ramp)
+
+	// 3. 组装一帧并软件渲染
+	frame := render.Frame{
+		Grid:  grid,
+		Style: render.PetStyle{Mode: cfg.DisplayMode, Theme: th, ShowColor: cfg.ShowColor, Stressed: *stressed},
+	}
+	if *withHUD {
+		w, _ := render.PetSize(grid, cfg.DisplayMode)
+		frame.HUD = snapshotHUD(w / 4)
+	}
+	if *withMenu {
+		frame.Menu = &render.MenuState{
+			ShowColor:   cfg.ShowColor,
+			ShowMonitor: cfg.ShowMonitor,
+			Mode:        cfg.DisplayMode,
+			Profile:     strings.ToUpper(cfg.Profile),
+			Theme:       strings.ToUpper(th.Name),
+		}
+		if frame.Menu.Profile == "" {
+			frame.Menu.Profile = "-"
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, render.Snapshot(fonts, frame)); err != nil {
+		return err
+	}
+	return fsutil.WriteAtomic(*out, buf.Bytes(), 0644)
+}
+
+// snapshotHUD 采样一次本机指标生成 HUD 文本 (没有历史数据，所以不带火花线)
+func snapshotHUD(cols int) []string {
+	if cols < 16 {
+		cols = 16
+	}
+	m, err := monitor.LocalSource().Sample()
+	if err != nil {
+		log.Println("采样指标失败:", err)
+	}
+	return []string{
+		hud.MeterLine("CPU", m.CPU, nil, cols),
+		hud.MeterLine("MEM", m.Mem, nil, cols),
+		hud.MeterLine("SWAP", m.Swap, nil, cols),
+	}
+}
+
+// runPet 以桌宠窗口的形式运行 Manager，直到窗口关闭；收到 SIGINT/SIGTERM 时先保存配置再退出
+func runPet(g *game.Manager) error {
+	defer g.Close()
+
+	// 保存要在主循环里做 (和菜单、拖拽改配置的是同一个协程)，这里只通知它；
+	// 之后再收到信号就按默认方式直接结束，以防主循环卡住
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-sig:
+			log.Println("收到退出信号，保存配置后退出")
+			signal.Stop(sig)
+			g.Quit()
+		case <-done:
+		}
+	}()
+
+	ebiten.SetWindowDecorated(false)
+	ebiten.SetScreenTransparent(true)
+	ebiten.SetWindowFloating(true)
+	return ebiten.RunGame(g)
 }